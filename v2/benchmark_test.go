@@ -0,0 +1,147 @@
+package sigolo
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func newDisabledLogger() *Logger {
+	logger, _ := newBufferLogger(LOG_ERROR)
+	return logger
+}
+
+func expensiveValue() string {
+	return fmt.Sprintf("%064d", 42)
+}
+
+func BenchmarkLoggerDisabledDebug(b *testing.B) {
+	logger := newDisabledLogger()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		logger.Debug("some message")
+	}
+}
+
+func BenchmarkLoggerDisabledDebugf(b *testing.B) {
+	logger := newDisabledLogger()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		logger.Debugf("some %s message with %d%%", "formatted", 100)
+	}
+}
+
+func BenchmarkLoggerDisabledDebugfLazy(b *testing.B) {
+	logger := newDisabledLogger()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		logger.Debugf("expensive value: %s", expensiveValue)
+	}
+}
+
+func BenchmarkDisabledDebugf(b *testing.B) {
+	SetDefaultLogLevel(LOG_ERROR)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Debugf("some %s message with %d%%", "formatted", 100)
+	}
+}
+
+func newDiscardLogger() *Logger {
+	logger := NewLoggerf(LOG_INFO, LogDefault)
+	for level := range logger.LevelOutputs {
+		logger.LevelOutputs[level] = io.Discard
+	}
+	return logger
+}
+
+func BenchmarkLoggerInfo(b *testing.B) {
+	logger := newDiscardLogger()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		logger.Info("some message")
+	}
+}
+
+func BenchmarkLoggerInfof(b *testing.B) {
+	logger := newDiscardLogger()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		logger.Infof("some %s message with %d%%", "formatted", 100)
+	}
+}
+
+func BenchmarkLoggerInfoParallel(b *testing.B) {
+	logger := newDiscardLogger()
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			logger.Info("some message")
+		}
+	})
+}
+
+func BenchmarkLegacyLoggerInfo(b *testing.B) {
+	logger := newDiscardLogger()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		legacyInfof(logger, "%s", "some message")
+	}
+}
+
+func BenchmarkLegacyLoggerInfof(b *testing.B) {
+	logger := newDiscardLogger()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		legacyInfof(logger, "some %s message with %d%%", "formatted", 100)
+	}
+}
+
+func BenchmarkLegacyLoggerInfoParallel(b *testing.B) {
+	logger := newDiscardLogger()
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			legacyInfof(logger, "%s", "some message")
+		}
+	})
+}
+
+// The legacy* functions are the implementation before pooled buffers and append-style formatting were introduced. They
+// are only kept to compare the current implementation against.
+
+func legacyInfof(l *Logger, format string, args ...interface{}) {
+	if l.LogLevel > LOG_INFO {
+		return
+	}
+	legacyLog(l, LOG_INFO, 3, l.LogTraceId, fmt.Sprintf(format, args...))
+}
+
+func legacyLog(l *Logger, level Level, framesBackward int, traceId TraceId, message string) {
+	caller := legacyGetCallerDetails(framesBackward)
+
+	updateCallerColumnWidth(caller)
+
+	legacyLogDefault(l.LevelOutputs[level], time.Now().Format(l.DateFormat), l.LevelStrings[level], CallerColumnWidth, caller, traceId, message)
+}
+
+func legacyGetCallerDetails(framesBackwards int) string {
+	name := "???"
+	line := -1
+	ok := false
+
+	_, name, line, ok = runtime.Caller(framesBackwards)
+
+	if ok {
+		name = path.Base(name)
+	}
+
+	return fmt.Sprintf("%s:%d", name, line)
+}
+
+func legacyLogDefault(writer io.Writer, time string, level string, maxLength int, caller string, traceId TraceId, message string) {
+	fmt.Fprintf(writer, "%s %s %-*s | #%x | %s\n", time, level, maxLength, caller, traceId.Low, message)
+}
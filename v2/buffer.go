@@ -0,0 +1,30 @@
+package sigolo
+
+import (
+	"sync"
+)
+
+// maxPooledBufferSize is the maximum capacity of a buffer that is put back into the pool. Larger buffers are left to the
+// garbage collector, so that a single huge entry doesn't keep its memory forever.
+const maxPooledBufferSize = 64 * 1024
+
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		buffer := make([]byte, 0, 256)
+		return &buffer
+	},
+}
+
+// getBuffer returns an empty buffer from the pool. Return it with putBuffer when it's not needed anymore.
+func getBuffer() *[]byte {
+	buffer := bufferPool.Get().(*[]byte)
+	*buffer = (*buffer)[:0]
+	return buffer
+}
+
+func putBuffer(buffer *[]byte) {
+	if cap(*buffer) > maxPooledBufferSize {
+		return
+	}
+	bufferPool.Put(buffer)
+}
@@ -0,0 +1,119 @@
+package sigolo
+
+import (
+	"fmt"
+	"sync"
+)
+
+// entryBuffer keeps the entries of a buffered logger until they are committed or discarded. When it's full, the oldest
+// entries are dropped.
+type entryBuffer struct {
+	mutex      sync.Mutex
+	entries    []Entry
+	first      int
+	count      int
+	dropped    int
+	maxEntries int
+}
+
+// NewBufferedLogger creates a logger like NewLogger does, but all entries of all levels are kept in memory until Commit
+// or Discard is called. At most maxEntries are kept, older entries are dropped.
+//
+// This is useful for request-scoped loggers: When a request fails, Commit writes everything that happened. When it
+// succeeds, Discard only writes the entries allowed by the log level of the logger, e.g. one INFO line per request.
+func NewBufferedLogger(maxEntries int) *Logger {
+	logger := NewLogger()
+	logger.entryBuffer = newEntryBuffer(maxEntries)
+	return logger
+}
+
+func newEntryBuffer(maxEntries int) *entryBuffer {
+	if maxEntries < 1 {
+		maxEntries = 1
+	}
+	return &entryBuffer{
+		maxEntries: maxEntries,
+	}
+}
+
+// Commit writes all buffered entries regardless of the log level. Entries logged afterwards are buffered again.
+func (l *Logger) Commit() {
+	if l.entryBuffer == nil {
+		return
+	}
+
+	entries, dropped := l.entryBuffer.take()
+	if dropped > 0 {
+		l.write(&Entry{
+			Time:    l.now(),
+			Level:   LOG_WARN,
+			Caller:  formatCallerOf(getCallerProgramCounter(2), l.CallerFormat),
+			TraceId: l.LogTraceId,
+			SpanId:  l.LogSpanId,
+			Message: fmt.Sprintf("Dropped %d older entries because the buffer was full", dropped),
+		})
+	}
+
+	for i := range entries {
+		l.write(&entries[i])
+	}
+}
+
+// Discard only writes the buffered entries allowed by the log level and drops all others. Entries logged afterwards are
+// buffered again.
+func (l *Logger) Discard() {
+	if l.entryBuffer == nil {
+		return
+	}
+
+	entries, _ := l.entryBuffer.take()
+	for i := range entries {
+		if l.ShouldLog(entries[i].Level) {
+			l.write(&entries[i])
+		}
+	}
+}
+
+func (b *entryBuffer) add(entry *Entry) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.entries == nil {
+		b.entries = make([]Entry, 0, min(b.maxEntries, 64))
+	}
+
+	if b.count < b.maxEntries {
+		if len(b.entries) < b.maxEntries {
+			b.entries = append(b.entries, *entry)
+		} else {
+			b.entries[(b.first+b.count)%b.maxEntries] = *entry
+		}
+		b.count++
+		return
+	}
+
+	// Full: Overwrite the oldest entry
+	b.entries[b.first] = *entry
+	b.first = (b.first + 1) % b.maxEntries
+	b.dropped++
+}
+
+// take returns the buffered entries in their original order together with the number of dropped entries and clears the
+// buffer.
+func (b *entryBuffer) take() ([]Entry, int) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	entries := make([]Entry, b.count)
+	for i := range entries {
+		entries[i] = b.entries[(b.first+i)%len(b.entries)]
+	}
+	dropped := b.dropped
+
+	b.entries = b.entries[:0]
+	b.first = 0
+	b.count = 0
+	b.dropped = 0
+
+	return entries, dropped
+}
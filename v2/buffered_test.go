@@ -0,0 +1,74 @@
+package sigolo
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func newBufferedTestLogger(maxEntries int) (*Logger, *bytes.Buffer) {
+	output := &bytes.Buffer{}
+	logger := NewBufferedLogger(maxEntries)
+	logger.LogLevel = LOG_INFO
+	for level := range logger.LevelOutputs {
+		logger.LevelOutputs[level] = output
+	}
+	return logger, output
+}
+
+func TestBufferedLoggerCommit(t *testing.T) {
+	logger, output := newBufferedTestLogger(10)
+
+	logger.Debug("debug")
+	logger.Infof("info %d", 1)
+	logger.Error("error")
+	assertTrue(t, output.Len() == 0)
+
+	logger.Commit()
+
+	lines := strings.Split(strings.TrimSuffix(output.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 lines but got %d: %q", len(lines), output.String())
+	}
+	assertTrue(t, strings.HasSuffix(lines[0], "| debug"))
+	assertTrue(t, strings.HasSuffix(lines[1], "| info 1"))
+	assertTrue(t, strings.HasSuffix(lines[2], "| error"))
+}
+
+func TestBufferedLoggerDiscard(t *testing.T) {
+	logger, output := newBufferedTestLogger(10)
+
+	logger.Trace("trace")
+	logger.Debug("debug")
+	logger.Info("summary")
+
+	logger.Discard()
+
+	assertTrue(t, strings.Count(output.String(), "\n") == 1)
+	assertTrue(t, strings.HasSuffix(output.String(), "| summary\n"))
+
+	// The buffer is empty again afterwards
+	logger.Commit()
+	assertTrue(t, strings.Count(output.String(), "\n") == 1)
+}
+
+func TestBufferedLoggerMaxEntries(t *testing.T) {
+	logger, output := newBufferedTestLogger(2)
+	logger.AddHook(FieldHook("user", "foo"), LOG_DEBUG)
+
+	for i := 0; i < 5; i++ {
+		logger.Debugf("debug %d", i)
+	}
+
+	logger.Commit()
+
+	lines := strings.Split(strings.TrimSuffix(output.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 lines but got %d: %q", len(lines), output.String())
+	}
+	assertTrue(t, strings.Contains(lines[0], "[WARN]"))
+	assertTrue(t, strings.Contains(lines[0], "buffered_test.go"))
+	assertTrue(t, strings.HasSuffix(lines[0], "| Dropped 3 older entries because the buffer was full"))
+	assertTrue(t, strings.HasSuffix(lines[1], "| debug 3 user=foo"))
+	assertTrue(t, strings.HasSuffix(lines[2], "| debug 4 user=foo"))
+}
@@ -0,0 +1,217 @@
+package sigolo
+
+import (
+	"path"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+)
+
+// CallerFormat determines how the caller of a log function is shown.
+type CallerFormat int
+
+const (
+	// CALLER_FILE shows the file name and line, e.g. "main.go:42".
+	CALLER_FILE CallerFormat = iota
+	// CALLER_FULL_PATH shows the full path of the file and the line, e.g. "/home/foo/app/cmd/main.go:42".
+	CALLER_FULL_PATH
+	// CALLER_MODULE_PATH shows the path of the file relative to its module and the line, e.g. "cmd/main.go:42". Files
+	// not belonging to any known module (like the standard library) are shown with their package path.
+	CALLER_MODULE_PATH
+	// CALLER_FUNCTION shows the function name and line, e.g. "main.handleRequest:42".
+	CALLER_FUNCTION
+
+	callerFormatCount
+)
+
+// callerInfo contains the resolved information of one call site. It's determined only once per program counter and then
+// taken from the callerCache.
+type callerInfo struct {
+	file      string
+	line      int
+	function  string
+	formatted [callerFormatCount]string
+}
+
+// sigoloFunctionPrefix is the beginning of the names of all functions of this package.
+const sigoloFunctionPrefix = "github.com/hauke96/sigolo/v2."
+
+var (
+	// callerCache maps a program counter to its *callerInfo
+	callerCache sync.Map
+
+	modulePaths     []string
+	mainPackagePath string
+	modulePathsOnce sync.Once
+)
+
+// getCallerDetails returns the formatted caller of the given frame. Like runtime.Caller, frame 0 is getCallerDetails
+// itself.
+func getCallerDetails(framesBackwards int, format CallerFormat) string {
+	return formatCallerOf(getCallerProgramCounter(framesBackwards+1), format)
+}
+
+// getCallerProgramCounter returns the program counter of the given frame or 0 if there's no such frame. Like
+// runtime.Caller, frame 0 is getCallerProgramCounter itself.
+func getCallerProgramCounter(framesBackwards int) uintptr {
+	var programCounters [1]uintptr
+	// Skip runtime.Callers itself, afterwards the frames are counted like runtime.Caller does
+	if runtime.Callers(framesBackwards+1, programCounters[:]) == 0 {
+		return 0
+	}
+	return programCounters[0]
+}
+
+// getCallerDetailsSkipping is equal to getCallerDetails(...) but skips all frames of functions starting with one of the
+// given prefixes, e.g. "net/http.".
+func getCallerDetailsSkipping(framesBackwards int, format CallerFormat, prefixes ...string) string {
+	var programCounters [32]uintptr
+	// Skip runtime.Callers itself, afterwards the frames are counted like runtime.Caller does
+	count := runtime.Callers(framesBackwards+1, programCounters[:])
+
+	for _, programCounter := range programCounters[:count] {
+		info := getCallerInfo(programCounter)
+		if !hasAnyPrefix(info.function, prefixes) {
+			return info.formatted[format]
+		}
+	}
+
+	return "???:-1"
+}
+
+func hasAnyPrefix(text string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(text, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatCallerOf returns the caller information of the program counter in the given format.
+func formatCallerOf(programCounter uintptr, format CallerFormat) string {
+	if programCounter == 0 {
+		return "???:-1"
+	}
+	return getCallerInfo(programCounter).formatted[format]
+}
+
+// getCallerInfo returns the information of the given program counter, which must be one returned by runtime.Callers.
+func getCallerInfo(programCounter uintptr) *callerInfo {
+	if info, ok := callerCache.Load(programCounter); ok {
+		return info.(*callerInfo)
+	}
+
+	frame, _ := runtime.CallersFrames([]uintptr{programCounter}).Next()
+	info := newCallerInfo(frame.File, frame.Line, frame.Function)
+
+	callerCache.Store(programCounter, info)
+	return info
+}
+
+func newCallerInfo(file string, line int, function string) *callerInfo {
+	info := &callerInfo{
+		file:     file,
+		line:     line,
+		function: function,
+	}
+
+	if file == "" {
+		for i := range info.formatted {
+			info.formatted[i] = "???:-1"
+		}
+		return info
+	}
+
+	info.formatted[CALLER_FILE] = formatCaller(path.Base(file), line)
+	info.formatted[CALLER_FULL_PATH] = formatCaller(file, line)
+	info.formatted[CALLER_MODULE_PATH] = formatCaller(moduleRelativePath(file, function), line)
+	info.formatted[CALLER_FUNCTION] = formatCaller(shortFunctionName(function), line)
+
+	return info
+}
+
+// packagePath extracts the package from a fully qualified function name like "github.com/foo/bar.(*Baz).Method".
+func packagePath(function string) string {
+	lastSlash := strings.LastIndexByte(function, '/')
+	firstDot := strings.IndexByte(function[lastSlash+1:], '.')
+	if firstDot == -1 {
+		return function
+	}
+	return function[:lastSlash+1+firstDot]
+}
+
+// shortFunctionName removes the package path but keeps the last path element, e.g. "bar.(*Baz).Method". For packages
+// ending with a major version like "github.com/foo/bar/v2", the element before the version is used.
+func shortFunctionName(function string) string {
+	pkg := packagePath(function)
+	name := function[len(pkg):]
+
+	lastElementStart := strings.LastIndexByte(pkg, '/') + 1
+	lastElement := pkg[lastElementStart:]
+	if lastElementStart > 0 && isMajorVersion(lastElement) {
+		pkg = pkg[:lastElementStart-1]
+		lastElement = pkg[strings.LastIndexByte(pkg, '/')+1:]
+	}
+
+	return lastElement + name
+}
+
+func isMajorVersion(element string) bool {
+	if len(element) < 2 || element[0] != 'v' {
+		return false
+	}
+	for _, c := range element[1:] {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// moduleRelativePath returns the path of the file relative to the module containing the function. When the module is
+// unknown, the package path is used instead.
+func moduleRelativePath(file string, function string) string {
+	pkg := packagePath(function)
+	if pkg == "" {
+		return path.Base(file)
+	}
+
+	modulePathsOnce.Do(loadModulePaths)
+
+	// Functions of the main package are named "main.foo" instead of using the actual package path
+	if pkg == "main" && mainPackagePath != "" {
+		pkg = mainPackagePath
+	}
+
+	longestModule := ""
+	for _, module := range modulePaths {
+		if len(module) > len(longestModule) && (pkg == module || strings.HasPrefix(pkg, module+"/")) {
+			longestModule = module
+		}
+	}
+
+	if longestModule == "" {
+		return pkg + "/" + path.Base(file)
+	}
+
+	relativePackage := strings.TrimPrefix(strings.TrimPrefix(pkg, longestModule), "/")
+	if relativePackage == "" {
+		return path.Base(file)
+	}
+	return relativePackage + "/" + path.Base(file)
+}
+
+func loadModulePaths() {
+	buildInfo, ok := debug.ReadBuildInfo()
+	if !ok {
+		return
+	}
+
+	mainPackagePath = buildInfo.Path
+	modulePaths = append(modulePaths, buildInfo.Main.Path)
+	for _, dependency := range buildInfo.Deps {
+		modulePaths = append(modulePaths, dependency.Path)
+	}
+}
@@ -0,0 +1,54 @@
+package sigolo
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCallerFormats(t *testing.T) {
+	logger, buffer := newBufferLogger(LOG_INFO)
+
+	logger.CallerFormat = CALLER_FILE
+	logger.Info("foo")
+	assertTrue(t, strings.Contains(buffer.String(), " caller_test.go:"))
+
+	buffer.Reset()
+	logger.CallerFormat = CALLER_FULL_PATH
+	logger.Info("foo")
+	assertTrue(t, strings.Contains(buffer.String(), "/v2/caller_test.go:"))
+
+	buffer.Reset()
+	logger.CallerFormat = CALLER_MODULE_PATH
+	logger.Info("foo")
+	assertTrue(t, strings.Contains(buffer.String(), " caller_test.go:"))
+	t.Log(buffer.String())
+
+	buffer.Reset()
+	logger.CallerFormat = CALLER_FUNCTION
+	logger.Info("foo")
+	assertTrue(t, strings.Contains(buffer.String(), " sigolo.TestCallerFormats:"))
+}
+
+func TestCallerCacheReturnsSameSite(t *testing.T) {
+	var callers []string
+	for i := 0; i < 2; i++ {
+		callers = append(callers, GetCallerDetails(1))
+	}
+
+	assertTrue(t, callers[0] == callers[1])
+	assertTrue(t, strings.HasPrefix(callers[0], "caller_test.go:"))
+}
+
+func TestModuleRelativePath(t *testing.T) {
+	modulePathsOnce.Do(loadModulePaths)
+
+	assertTrue(t, moduleRelativePath("/usr/lib/go/src/net/http/server.go", "net/http.(*conn).serve") == "net/http/server.go")
+	assertTrue(t, moduleRelativePath("/foo/unknown/bar.go", "example.com/unknown.Bar") == "example.com/unknown/bar.go")
+	assertTrue(t, moduleRelativePath("/foo/v2/sub/bar.go", "github.com/hauke96/sigolo/v2/sub.Bar") == "sub/bar.go")
+}
+
+func TestShortFunctionName(t *testing.T) {
+	assertTrue(t, shortFunctionName("main.main") == "main.main")
+	assertTrue(t, shortFunctionName("net/http.(*conn).serve") == "http.(*conn).serve")
+	assertTrue(t, shortFunctionName("github.com/hauke96/sigolo/v2.Info") == "sigolo.Info")
+}
@@ -0,0 +1,201 @@
+package sigolo
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ChromeTrace records spans and log entries and writes them in the Chrome Trace Event Format when it's closed. The
+// result can be loaded into chrome://tracing or https://ui.perfetto.dev to see a timeline of e.g. a batch job.
+//
+// Each trace ID becomes its own thread of the timeline, or each goroutine when GroupByGoroutine is set. Spans are shown
+// as begin and end events, so spans of the same thread should be nested. Log entries are shown as instant events. All
+// events are kept in memory until Close is called.
+type ChromeTrace struct {
+	// GroupByGoroutine uses the goroutine instead of the trace ID as thread of an event. Determining the goroutine costs
+	// some time for each event.
+	GroupByGoroutine bool
+
+	writer    io.Writer
+	processId int
+
+	mutex       sync.Mutex
+	events      []chromeTraceEvent
+	threadIds   map[string]int
+	threadNames []string
+	closed      bool
+}
+
+// chromeTraceEvent is one event of the Chrome Trace Event Format, see
+// https://docs.google.com/document/d/1CvAClvFfyA5R-PhYUmn5OOQtYMH4h6I0nSsKchNAySU
+type chromeTraceEvent struct {
+	Name      string                 `json:"name"`
+	Category  string                 `json:"cat,omitempty"`
+	Phase     string                 `json:"ph"`
+	Timestamp float64                `json:"ts"`
+	ProcessId int                    `json:"pid"`
+	ThreadId  int                    `json:"tid"`
+	Scope     string                 `json:"s,omitempty"`
+	Args      map[string]interface{} `json:"args,omitempty"`
+}
+
+// NewChromeTrace creates a trace written to the given writer on Close.
+func NewChromeTrace(writer io.Writer) *ChromeTrace {
+	return &ChromeTrace{
+		writer:    writer,
+		processId: os.Getpid(),
+		threadIds: map[string]int{},
+	}
+}
+
+// SetDefaultChromeTrace records the spans and entries of the default logger in the given trace. Use nil to disable it.
+func SetDefaultChromeTrace(trace *ChromeTrace) {
+	chromeTrace = trace
+	DefaultLogger = GetLoggerWithCurrentDefaults()
+}
+
+// threadId returns the thread of an event of the given trace ID. The caller must hold the mutex.
+func (c *ChromeTrace) threadId(traceId TraceId) int {
+	name := "#" + traceId.String()
+	if c.GroupByGoroutine {
+		name = "goroutine " + strconv.FormatUint(currentGoroutineId(), 10)
+	}
+
+	threadId, ok := c.threadIds[name]
+	if !ok {
+		threadId = len(c.threadNames) + 1
+		c.threadIds[name] = threadId
+		c.threadNames = append(c.threadNames, name)
+	}
+	return threadId
+}
+
+func (c *ChromeTrace) add(event chromeTraceEvent, traceId TraceId) int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.closed {
+		return 0
+	}
+	if event.ThreadId == 0 {
+		event.ThreadId = c.threadId(traceId)
+	}
+	event.ProcessId = c.processId
+	c.events = append(c.events, event)
+	return event.ThreadId
+}
+
+// recordEntry adds the entry as instant event.
+func (c *ChromeTrace) recordEntry(entry *Entry) {
+	args := map[string]interface{}{
+		"caller": entry.Caller,
+	}
+	if entry.SpanId != 0 {
+		args["span_id"] = entry.SpanId.String()
+	}
+	for _, field := range entry.Fields {
+		args[field.Key] = formatFieldValue(field.Value)
+	}
+
+	c.add(chromeTraceEvent{
+		Name:      entry.Message,
+		Category:  entry.Level.String(),
+		Phase:     "i",
+		Timestamp: chromeTraceTimestamp(entry.Time),
+		Scope:     "t",
+		Args:      args,
+	}, entry.TraceId)
+}
+
+// beginSpan adds the begin event of the span and returns the thread it belongs to, so that the end event is put on the
+// same thread.
+func (c *ChromeTrace) beginSpan(span *Span) int {
+	return c.add(chromeTraceEvent{
+		Name:      span.Name,
+		Category:  "span",
+		Phase:     "B",
+		Timestamp: chromeTraceTimestamp(span.start),
+		Args: map[string]interface{}{
+			"span_id": span.LogSpanId.String(),
+		},
+	}, span.LogTraceId)
+}
+
+func (c *ChromeTrace) endSpan(span *Span, threadId int, end time.Time, status string) {
+	if threadId == 0 {
+		return
+	}
+	c.add(chromeTraceEvent{
+		Name:      span.Name,
+		Category:  "span",
+		Phase:     "E",
+		Timestamp: chromeTraceTimestamp(end),
+		ThreadId:  threadId,
+		Args: map[string]interface{}{
+			"status": status,
+		},
+	}, span.LogTraceId)
+}
+
+// chromeTraceTimestamp returns the time in microseconds.
+func chromeTraceTimestamp(t time.Time) float64 {
+	return float64(t.UnixNano()) / 1000
+}
+
+// Close writes all recorded events and closes the writer if it's an io.Closer. Events recorded afterwards are ignored.
+func (c *ChromeTrace) Close() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+
+	events := make([]chromeTraceEvent, 0, len(c.threadNames)+len(c.events))
+	for i, name := range c.threadNames {
+		events = append(events, chromeTraceEvent{
+			Name:      "thread_name",
+			Phase:     "M",
+			ProcessId: c.processId,
+			ThreadId:  i + 1,
+			Args:      map[string]interface{}{"name": name},
+		})
+	}
+	events = append(events, c.events...)
+	c.events = nil
+
+	data, err := json.Marshal(struct {
+		TraceEvents     []chromeTraceEvent `json:"traceEvents"`
+		DisplayTimeUnit string             `json:"displayTimeUnit"`
+	}{events, "ms"})
+	if err == nil {
+		_, err = c.writer.Write(data)
+	}
+
+	if closer, ok := c.writer.(io.Closer); ok {
+		closeErr := closer.Close()
+		if err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// currentGoroutineId parses the ID from the first line of the stack trace, which looks like "goroutine 42 [running]:".
+func currentGoroutineId() uint64 {
+	var buffer [64]byte
+	stack := buffer[:runtime.Stack(buffer[:], false)]
+	stack = bytes.TrimPrefix(stack, []byte("goroutine "))
+	if end := bytes.IndexByte(stack, ' '); end != -1 {
+		stack = stack[:end]
+	}
+	id, _ := strconv.ParseUint(string(stack), 10, 64)
+	return id
+}
@@ -0,0 +1,79 @@
+package sigolo
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+)
+
+type chromeTraceFile struct {
+	TraceEvents []chromeTraceEvent `json:"traceEvents"`
+}
+
+func readChromeTrace(t *testing.T, buffer *bytes.Buffer) []chromeTraceEvent {
+	var file chromeTraceFile
+	if err := json.Unmarshal(buffer.Bytes(), &file); err != nil {
+		t.Fatalf("Invalid JSON %q: %v", buffer.String(), err)
+	}
+	return file.TraceEvents
+}
+
+func TestChromeTrace(t *testing.T) {
+	clock := NewFakeClock(time.UnixMilli(1000))
+	logger, _ := newBufferLogger(LOG_INFO)
+	logger.Clock = clock
+	logger.LogTraceId = SequentialTraceId(0x2a)
+	output := &bytes.Buffer{}
+	logger.ChromeTrace = NewChromeTrace(output)
+
+	span := logger.Span("load")
+	clock.Advance(time.Millisecond)
+	span.Info("inside")
+	clock.Advance(time.Millisecond)
+	span.End()
+	logger.Debug("not logged")
+
+	assertTrue(t, logger.ChromeTrace.Close() == nil)
+	logger.Info("after close")
+
+	events := readChromeTrace(t, output)
+	if len(events) != 6 {
+		t.Fatalf("Expected 6 events but got %q", output.String())
+	}
+
+	assertTrue(t, events[0].Phase == "M" && events[0].Args["name"] == "#2a")
+	assertTrue(t, events[1].Phase == "B" && events[1].Name == "load" && events[1].Timestamp == 1000000)
+	assertTrue(t, events[2].Phase == "i" && events[2].Name == "Span load started")
+	assertTrue(t, events[3].Phase == "i" && events[3].Name == "inside" && events[3].Category == "INFO")
+	assertTrue(t, events[3].Timestamp == 1001000 && events[3].Args["span_id"] == span.LogSpanId.String())
+	assertTrue(t, events[4].Phase == "E" && events[4].Timestamp == 1002000 && events[4].Args["status"] == "ok")
+	assertTrue(t, events[5].Phase == "i" && events[5].Name == "Span load ended after 2ms: ok")
+	for _, event := range events {
+		assertTrue(t, event.ThreadId == 1)
+	}
+}
+
+func TestChromeTraceThreads(t *testing.T) {
+	logger, _ := newBufferLogger(LOG_INFO)
+	output := &bytes.Buffer{}
+	logger.ChromeTrace = NewChromeTrace(output)
+	logger.ChromeTrace.GroupByGoroutine = true
+
+	logger.Info("main")
+	waitGroup := sync.WaitGroup{}
+	waitGroup.Add(1)
+	go func() {
+		logger.Info("other")
+		waitGroup.Done()
+	}()
+	waitGroup.Wait()
+	logger.ChromeTrace.Close()
+
+	events := readChromeTrace(t, output)
+	assertTrue(t, len(events) == 4)
+	assertTrue(t, events[0].Phase == "M" && events[1].Phase == "M")
+	assertTrue(t, events[2].ThreadId == 1 && events[3].ThreadId == 2)
+	assertTrue(t, currentGoroutineId() != 0)
+}
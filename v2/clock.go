@@ -0,0 +1,136 @@
+package sigolo
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Clock provides the time of log entries. Use a FakeClock to get deterministic timestamps in tests or to replay entries
+// with historical times.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// SystemClock is the default clock using time.Now().
+var SystemClock Clock = systemClock{}
+
+// FakeClock is a clock that only changes when it's told to.
+type FakeClock struct {
+	mutex sync.Mutex
+	now   time.Time
+	step  time.Duration
+}
+
+// NewFakeClock creates a clock returning the given time until it's changed by Set or Advance.
+func NewFakeClock(start time.Time) *FakeClock {
+	return &FakeClock{now: start}
+}
+
+// Now returns the current time of the clock and afterwards advances it by the step, if set.
+func (c *FakeClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := c.now
+	c.now = c.now.Add(c.step)
+	return now
+}
+
+// Set changes the current time of the clock.
+func (c *FakeClock) Set(now time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.now = now
+}
+
+// Advance moves the clock forward by the given duration.
+func (c *FakeClock) Advance(duration time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.now = c.now.Add(duration)
+}
+
+// SetStep lets the clock advance by the given duration each time Now is called, so that consecutive entries get
+// different times.
+func (c *FakeClock) SetStep(step time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.step = step
+}
+
+// TimestampMode determines what the timestamp of an entry shows.
+type TimestampMode int
+
+const (
+	// TIMESTAMP_ABSOLUTE shows the time of the entry formatted with the DateFormat of the logger.
+	TIMESTAMP_ABSOLUTE TimestampMode = iota
+	// TIMESTAMP_ELAPSED shows the time since the mode was set, e.g. "   12.345s".
+	TIMESTAMP_ELAPSED
+	// TIMESTAMP_DELTA shows the time since the previous entry of the logger, e.g. "+   0.012s".
+	TIMESTAMP_DELTA
+)
+
+// timestamper keeps the state of relative timestamps. It's shared by all copies of a logger.
+type timestamper struct {
+	mode  TimestampMode
+	start time.Time
+
+	mutex    sync.Mutex
+	previous time.Time
+}
+
+func newTimestamper(mode TimestampMode, start time.Time) *timestamper {
+	if mode == TIMESTAMP_ABSOLUTE {
+		return nil
+	}
+	return &timestamper{
+		mode:     mode,
+		start:    start,
+		previous: start,
+	}
+}
+
+// SetTimestampMode determines what the timestamps of this logger show. Relative timestamps start at the current time
+// of the clock.
+func (l *Logger) SetTimestampMode(mode TimestampMode) {
+	l.timestamper = newTimestamper(mode, l.now())
+}
+
+// now returns the current time of the clock of this logger.
+func (l *Logger) now() time.Time {
+	if l.Clock == nil {
+		return time.Now()
+	}
+	return l.Clock.Now()
+}
+
+// formatTime returns the timestamp of the entry according to the location and timestamp mode of this logger.
+func (l *Logger) formatTime(t time.Time) string {
+	if l.timestamper != nil {
+		return l.timestamper.format(t)
+	}
+	if l.TimeLocation != nil {
+		t = t.In(l.TimeLocation)
+	}
+	return formatTimestamp(t, l.DateFormat)
+}
+
+func (t *timestamper) format(now time.Time) string {
+	if t.mode == TIMESTAMP_ELAPSED {
+		return fmt.Sprintf("%10.3fs", now.Sub(t.start).Seconds())
+	}
+
+	t.mutex.Lock()
+	delta := now.Sub(t.previous)
+	t.previous = now
+	t.mutex.Unlock()
+
+	return fmt.Sprintf("+%9.3fs", delta.Seconds())
+}
@@ -0,0 +1,111 @@
+package sigolo
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFakeClockGoldenOutput(t *testing.T) {
+	logger, buffer := newBufferLogger(LOG_INFO)
+	logger.Clock = NewFakeClock(time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC))
+	logger.LogTraceId = SequentialTraceId(0x2a)
+
+	logger.Info("foo")
+
+	assertTrue(t, strings.HasPrefix(buffer.String(), "2024-03-01 12:30:00.000 [INFO]"))
+	assertTrue(t, strings.HasSuffix(buffer.String(), "#2a | foo\n"))
+}
+
+func TestTimeLocation(t *testing.T) {
+	start := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	location := time.FixedZone("UTC+2", 2*60*60)
+
+	logger, buffer := newBufferLogger(LOG_INFO)
+	logger.Clock = NewFakeClock(start.In(location))
+	logger.Info("local")
+	logger.TimeLocation = time.UTC
+	logger.Info("utc")
+	logger.TimeLocation = location
+	logger.Info("explicit")
+
+	lines := strings.Split(buffer.String(), "\n")
+	assertTrue(t, strings.HasPrefix(lines[0], "2024-03-01 14:30:00.000"))
+	assertTrue(t, strings.HasPrefix(lines[1], "2024-03-01 12:30:00.000"))
+	assertTrue(t, strings.HasPrefix(lines[2], "2024-03-01 14:30:00.000"))
+}
+
+func TestTimestampModes(t *testing.T) {
+	clock := NewFakeClock(time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC))
+	logger, buffer := newBufferLogger(LOG_INFO)
+	logger.Clock = clock
+
+	logger.SetTimestampMode(TIMESTAMP_ELAPSED)
+	clock.Advance(1500 * time.Millisecond)
+	logger.Info("elapsed")
+
+	logger.SetTimestampMode(TIMESTAMP_DELTA)
+	clock.SetStep(250 * time.Millisecond)
+	clock.Advance(time.Second)
+	logger.Info("first")
+	logger.Info("second")
+
+	lines := strings.Split(buffer.String(), "\n")
+	assertTrue(t, strings.HasPrefix(lines[0], "     1.500s [INFO]"))
+	assertTrue(t, strings.HasPrefix(lines[1], "+    1.000s [INFO]"))
+	assertTrue(t, strings.HasPrefix(lines[2], "+    0.250s [INFO]"))
+
+	// Copies of the logger share the previous time
+	child := *logger
+	child.LogTraceId.Low++
+	child.Info("third")
+	assertTrue(t, strings.HasPrefix(strings.Split(buffer.String(), "\n")[3], "+    0.250s [INFO]"))
+}
+
+func TestDefaultClock(t *testing.T) {
+	buffer := prepareBuffer(t, LOG_INFO)
+	SetDefaultClock(NewFakeClock(time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)))
+	SetDefaultTimeLocation(time.UTC)
+	defer func() {
+		SetDefaultClock(nil)
+		SetDefaultTimeLocation(nil)
+	}()
+
+	Info("foo")
+
+	assertTrue(t, strings.HasPrefix(buffer.String(), "2024-03-01 12:30:00.000 [INFO]"))
+}
+
+func TestThrottleUsesClock(t *testing.T) {
+	clock := NewFakeClock(time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC))
+	logger, buffer := newThrottleTestLogger()
+	logger.Clock = clock
+	throttled := logger.EveryDuration(time.Minute)
+
+	for i := 0; i < 3; i++ {
+		throttled.Info("foo")
+		clock.Advance(40 * time.Second)
+	}
+
+	assertTrue(t, countLines(buffer.String()) == 3)
+	assertTrue(t, strings.Contains(buffer.String(), "Suppressed 1 similar messages"))
+}
+
+func TestClockIsReadOncePerEntry(t *testing.T) {
+	start := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	clock := NewFakeClock(start)
+	clock.SetStep(time.Second)
+	logger, buffer := newThrottleTestLogger()
+	logger.Clock = clock
+	logger.Recorder = NewFlightRecorder(10)
+	logger.Recorder.DumpLevel = LOG_FATAL
+	throttled := logger.EveryDuration(time.Millisecond)
+
+	throttled.Info("first")
+	throttled.Info("second")
+
+	lines := strings.Split(buffer.String(), "\n")
+	assertTrue(t, strings.HasPrefix(lines[0], "2024-03-01 12:30:00.000 [INFO]"))
+	assertTrue(t, strings.HasPrefix(lines[1], "2024-03-01 12:30:01.000 [INFO]"))
+	assertTrue(t, clock.Now().Equal(start.Add(2*time.Second)))
+}
@@ -0,0 +1,88 @@
+package sigolo
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// CommandMaxLineLength is the maximum length of a line printed by a child process before it gets split into several
+// entries.
+var CommandMaxLineLength = 64 * 1024
+
+// CommandWriters are the writers receiving the output of a child process. Call Flush after the process finished to log
+// incomplete last lines.
+type CommandWriters struct {
+	Stdout *LevelWriter
+	Stderr *LevelWriter
+}
+
+func (c *CommandWriters) Flush() {
+	c.Stdout.Flush()
+	c.Stderr.Flush()
+}
+
+// AttachCommand connects the stdout and stderr of the command to the DefaultLogger. See Logger.AttachCommand for
+// details.
+func AttachCommand(cmd *exec.Cmd, name string) *CommandWriters {
+	// Use a copy so that all lines of the child have the same trace ID.
+	logger := *DefaultLogger
+	writers := logger.attachCommand(cmd, name, GetCallerDetails(2))
+	increaseTraceId()
+	return writers
+}
+
+// RunCommand runs the command and logs its output using the DefaultLogger. See Logger.RunCommand for details.
+func RunCommand(cmd *exec.Cmd, name string) error {
+	logger := *DefaultLogger
+	writers := logger.attachCommand(cmd, name, GetCallerDetails(2))
+	increaseTraceId()
+
+	err := cmd.Run()
+	writers.Flush()
+	return err
+}
+
+// AttachCommand connects the stdout of the command to this logger using the INFO level and stderr using the WARN level.
+// Each line is prefixed with the given name and logged with the trace ID of this logger. The trace ID is also passed to
+// the child process (see TraceIdEnvironmentVariable). This must be called before the command is started.
+func (l *Logger) AttachCommand(cmd *exec.Cmd, name string) *CommandWriters {
+	return l.attachCommand(cmd, name, getCallerDetails(2, l.CallerFormat))
+}
+
+// RunCommand is equal to AttachCommand(...) but also runs the command and waits for it to finish.
+func (l *Logger) RunCommand(cmd *exec.Cmd, name string) error {
+	writers := l.attachCommand(cmd, name, getCallerDetails(2, l.CallerFormat))
+
+	err := cmd.Run()
+	writers.Flush()
+	return err
+}
+
+func (l *Logger) attachCommand(cmd *exec.Cmd, name string, caller string) *CommandWriters {
+	writers := &CommandWriters{
+		Stdout: l.newCommandWriter(LOG_INFO, name, caller),
+		Stderr: l.newCommandWriter(LOG_WARN, name, caller),
+	}
+
+	cmd.Stdout = writers.Stdout
+	cmd.Stderr = writers.Stderr
+
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+	cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", TraceIdEnvironmentVariable, l.LogTraceId))
+
+	return writers
+}
+
+func (l *Logger) newCommandWriter(level Level, name string, caller string) *LevelWriter {
+	writer := l.Writer(level)
+	writer.Prefix = name + ": "
+	writer.MaxLineLength = CommandMaxLineLength
+	// The actual caller of Write is somewhere in the exec package, which isn't helpful.
+	writer.callerDetails = func(int, CallerFormat) string {
+		return caller
+	}
+	return writer
+}
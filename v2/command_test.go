@@ -0,0 +1,62 @@
+package sigolo
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestRunCommand(t *testing.T) {
+	if os.Getenv("SIGOLO_COMMAND_TEST") == "1" {
+		fmt.Printf("trace %s\n", os.Getenv(TraceIdEnvironmentVariable))
+		fmt.Fprint(os.Stderr, "some error")
+		fmt.Print("incomplete")
+		os.Exit(0)
+	}
+
+	logger, buffer := newBufferLogger(LOG_INFO)
+	logger.LogTraceId = SequentialTraceId(0x2a)
+
+	cmd := exec.Command(os.Args[0], "-test.run=TestRunCommand")
+	cmd.Env = append(os.Environ(), "SIGOLO_COMMAND_TEST=1")
+	err := logger.RunCommand(cmd, "child")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output := buffer.String()
+	assertTrue(t, strings.Contains(output, "[INFO]  command_test.go"))
+	assertTrue(t, strings.Contains(output, "| child: trace 2a\n"))
+	assertTrue(t, strings.Contains(output, "| child: incomplete\n"))
+	assertTrue(t, strings.Contains(output, "[WARN]  command_test.go"))
+	assertTrue(t, strings.Contains(output, "| child: some error\n"))
+}
+
+func TestChildDefaultLoggerKeepsInheritedTraceId(t *testing.T) {
+	if os.Getenv("SIGOLO_COMMAND_TEST") == "2" {
+		SetDefaultFormatFunctionAll(LogDefault)
+		Info("first")
+		Info("second")
+		NewLogger().Info("third")
+		os.Exit(0)
+	}
+
+	logger, buffer := newBufferLogger(LOG_INFO)
+	logger.LogTraceId = SequentialTraceId(0x2a)
+
+	cmd := exec.Command(os.Args[0], "-test.run=TestChildDefaultLoggerKeepsInheritedTraceId")
+	cmd.Env = append(os.Environ(), "SIGOLO_COMMAND_TEST=2")
+	err := logger.RunCommand(cmd, "child")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output := buffer.String()
+	assertTrue(t, strings.Contains(output, " | #2a | first\n"))
+	assertTrue(t, strings.Contains(output, " | #2a | second\n"))
+	// Only the DefaultLogger continues the trace of the parent.
+	assertTrue(t, strings.Contains(output, " | third\n"))
+	assertFalse(t, strings.Contains(output, " | #2a | third\n"))
+}
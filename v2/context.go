@@ -0,0 +1,16 @@
+package sigolo
+
+import "context"
+
+type loggerContextKey struct{}
+
+// ContextWithLogger returns a context carrying the logger, e.g. a logger created by NewLoggerFromRequest(...).
+func ContextWithLogger(ctx context.Context, logger *Logger) context.Context {
+	return context.WithValue(ctx, loggerContextKey{}, logger)
+}
+
+// LoggerFromContext returns the logger of the context or nil if there's none.
+func LoggerFromContext(ctx context.Context) *Logger {
+	logger, _ := ctx.Value(loggerContextKey{}).(*Logger)
+	return logger
+}
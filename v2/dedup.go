@@ -0,0 +1,175 @@
+package sigolo
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// messageDeduplicator collapses consecutive entries with the same level, caller and message. Only the first entry is
+// written, the number of repetitions is written as a separate entry later on.
+type messageDeduplicator struct {
+	window time.Duration
+
+	mutex sync.Mutex
+	// logger is the logger that wrote the last entry and is used to write the number of repetitions.
+	logger   *Logger
+	last     Entry
+	hasLast  bool
+	repeated int
+	timer    *time.Timer
+	// timerGeneration identifies the current timer, so that a timer firing after it was stopped or replaced does nothing.
+	timerGeneration int
+}
+
+// repetitions is the entry with the number of repetitions of the last entry. It's written after the mutex of the
+// deduplicator is unlocked, so that slow writers don't block other goroutines checking for repetitions.
+type repetitions struct {
+	logger *Logger
+	entry  Entry
+}
+
+var (
+	// pendingDeduplicators contains all deduplicators with unwritten repetitions, so that they can be flushed on exit.
+	pendingDeduplicatorsMutex sync.Mutex
+	pendingDeduplicators      = map[*messageDeduplicator]bool{}
+)
+
+func newMessageDeduplicator(window time.Duration) *messageDeduplicator {
+	if window <= 0 {
+		return nil
+	}
+	return &messageDeduplicator{
+		window: window,
+	}
+}
+
+// SetDeduplicationWindow enables collapsing of repeated messages. An entry with the same level, caller and message as
+// the previous one is not written when it occurs within the given window after the previous entry was written. Instead,
+// a line "Last message repeated N times" is written when the window is over, a different entry is logged or the
+// application exits due to a fatal error. A window of 0 disables it.
+func (l *Logger) SetDeduplicationWindow(window time.Duration) {
+	if l.deduplicator != nil {
+		l.deduplicator.flush()
+	}
+	l.deduplicator = newMessageDeduplicator(window)
+}
+
+// add returns true when the entry should be written and false when it's a repetition of the last entry. When the
+// entry ends a series of repetitions, their number must be written before the entry.
+func (d *messageDeduplicator) add(logger *Logger, entry *Entry) (repetitions, bool) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	timeSinceLast := entry.Time.Sub(d.last.Time)
+	if d.isRepetition(entry) && timeSinceLast < d.window {
+		if d.repeated == 0 {
+			setDeduplicatorPending(d, true)
+		}
+		d.repeated++
+		if d.timer == nil {
+			d.startTimer(d.window - timeSinceLast)
+		}
+		return repetitions{}, false
+	}
+
+	flushed := d.flushLocked()
+
+	d.logger = logger
+	d.last = *entry
+	d.hasLast = true
+	return flushed, true
+}
+
+func (d *messageDeduplicator) isRepetition(entry *Entry) bool {
+	return d.hasLast &&
+		d.last.Level == entry.Level &&
+		d.last.Caller == entry.Caller &&
+		d.last.Message == entry.Message
+}
+
+// startTimer flushes the repetitions after the given duration. The window is measured with the clock of the logger,
+// so the timer starts again when that clock hasn't reached the end of the window yet.
+func (d *messageDeduplicator) startTimer(duration time.Duration) {
+	d.timerGeneration++
+	generation := d.timerGeneration
+	d.timer = time.AfterFunc(duration, func() {
+		d.flushExpired(generation)
+	})
+}
+
+func (d *messageDeduplicator) flushExpired(generation int) {
+	d.mutex.Lock()
+	if d.timer == nil || generation != d.timerGeneration {
+		d.mutex.Unlock()
+		return
+	}
+	if remaining := d.window - d.logger.now().Sub(d.last.Time); remaining > 0 {
+		d.startTimer(remaining)
+		d.mutex.Unlock()
+		return
+	}
+	flushed := d.flushLocked()
+	d.mutex.Unlock()
+
+	flushed.write()
+}
+
+// flush writes the number of repetitions of the last entry, if there were any.
+func (d *messageDeduplicator) flush() {
+	d.mutex.Lock()
+	flushed := d.flushLocked()
+	d.mutex.Unlock()
+
+	flushed.write()
+}
+
+// flushLocked resets the repetitions and returns the entry with their number, which is empty when there were none.
+func (d *messageDeduplicator) flushLocked() repetitions {
+	if d.timer != nil {
+		d.timer.Stop()
+		d.timer = nil
+	}
+
+	if d.repeated == 0 {
+		return repetitions{}
+	}
+
+	entry := d.last
+	entry.Time = d.logger.now()
+	entry.Message = fmt.Sprintf("Last message repeated %d times", d.repeated)
+	d.repeated = 0
+	setDeduplicatorPending(d, false)
+
+	return repetitions{logger: d.logger, entry: entry}
+}
+
+func (r *repetitions) write() {
+	if r.logger != nil {
+		r.logger.write(&r.entry)
+	}
+}
+
+func setDeduplicatorPending(d *messageDeduplicator, pending bool) {
+	pendingDeduplicatorsMutex.Lock()
+	defer pendingDeduplicatorsMutex.Unlock()
+	if pending {
+		pendingDeduplicators[d] = true
+	} else {
+		delete(pendingDeduplicators, d)
+	}
+}
+
+// flushAllDeduplicators writes the repetitions of all loggers, e.g. before the application exits.
+func flushAllDeduplicators() {
+	pendingDeduplicatorsMutex.Lock()
+	deduplicators := make([]*messageDeduplicator, 0, len(pendingDeduplicators))
+	for d := range pendingDeduplicators {
+		deduplicators = append(deduplicators, d)
+	}
+	pendingDeduplicatorsMutex.Unlock()
+
+	for _, d := range deduplicators {
+		d.flush()
+	}
+}
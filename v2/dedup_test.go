@@ -0,0 +1,103 @@
+package sigolo
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDeduplicationOnDifferentMessage(t *testing.T) {
+	logger, buffer := newBufferLogger(LOG_INFO)
+	logger.SetDeduplicationWindow(time.Hour)
+
+	for i := 0; i < 4; i++ {
+		logger.Info("foo")
+	}
+	logger.Info("bar")
+
+	lines := strings.Split(strings.TrimSuffix(buffer.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 lines but got %d: %q", len(lines), buffer.String())
+	}
+	assertTrue(t, strings.HasSuffix(lines[0], "| foo"))
+	assertTrue(t, strings.HasSuffix(lines[1], "| Last message repeated 3 times"))
+	assertTrue(t, strings.HasSuffix(lines[2], "| bar"))
+}
+
+// syncBuffer is a buffer, which can be written by the timer of a deduplicator while the test reads it.
+type syncBuffer struct {
+	mutex  sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buffer.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buffer.String()
+}
+
+func TestDeduplicationOnTimer(t *testing.T) {
+	clock := NewFakeClock(time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC))
+	buffer := &syncBuffer{}
+	logger := NewLoggerl(LOG_INFO)
+	for level := range logger.LevelOutputs {
+		logger.LevelOutputs[level] = buffer
+	}
+	logger.Clock = clock
+	logger.SetDeduplicationWindow(10 * time.Millisecond)
+
+	for i := 0; i < 2; i++ {
+		logger.Info("foo")
+	}
+
+	// The window is measured with the clock of the logger, which hasn't moved yet.
+	time.Sleep(50 * time.Millisecond)
+	assertTrue(t, countLines(buffer.String()) == 1)
+
+	clock.Advance(time.Second)
+	for i := 0; i < 100 && countLines(buffer.String()) == 1; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assertTrue(t, strings.HasSuffix(buffer.String(), "| Last message repeated 1 times\n"))
+}
+
+func TestDeduplicationDifferentCallers(t *testing.T) {
+	logger, buffer := newBufferLogger(LOG_INFO)
+	logger.SetDeduplicationWindow(time.Hour)
+
+	logger.Info("foo")
+	logger.Info("foo")
+
+	assertTrue(t, countLines(buffer.String()) == 2)
+}
+
+func TestDeduplicationFlushedOnFatal(t *testing.T) {
+	if os.Getenv("SIGOLO_DEDUPLICATION_TEST") == "1" {
+		SetDefaultDeduplicationWindow(time.Hour)
+		logger := NewLogger()
+		logger.SetDeduplicationWindow(time.Hour)
+		for i := 0; i < 3; i++ {
+			Error("foo")
+			logger.Error("bar")
+		}
+		FatalCheck(os.ErrNotExist)
+		return
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=TestDeduplicationFlushedOnFatal")
+	cmd.Env = append(os.Environ(), "SIGOLO_DEDUPLICATION_TEST=1")
+	output, _ := cmd.CombinedOutput()
+
+	assertTrue(t, strings.Count(string(output), "| Last message repeated 2 times\n") == 2)
+	assertTrue(t, strings.Contains(string(output), "| file does not exist\n"))
+}
@@ -0,0 +1,118 @@
+package sigolo
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Escalation temporarily lowers the log level after a problem, so that the follow-up behaviour is captured: When an
+// entry with at least the TriggerLevel is logged, entries with at least the TargetLevel are logged for the given
+// Duration. Afterwards, no escalation starts until the Cooldown has passed. The start and end of an escalation are
+// logged with INFO level.
+//
+// The state is shared between all loggers using the same escalation, so an error of one request also makes the other
+// requests verbose. Entries of buffered loggers neither start an escalation nor announce its end.
+type Escalation struct {
+	TriggerLevel Level
+	TargetLevel  Level
+	Duration     time.Duration
+	Cooldown     time.Duration
+	// Clock determines when the escalation starts and ends. It's used instead of the clocks of the loggers, which may
+	// differ between the loggers sharing the escalation. When nil, time.Now() is used.
+	Clock Clock
+
+	// active is checked without locking the mutex, so that inactive escalations cost nothing.
+	active        atomic.Bool
+	mutex         sync.Mutex
+	until         time.Time
+	cooldownUntil time.Time
+	// ended is set when the escalation expired and the end hasn't been announced yet.
+	ended atomic.Bool
+}
+
+// NewEscalation creates an escalation, e.g. logging DEBUG entries for one minute after an ERROR but at most once every
+// ten minutes:
+//
+//	logger.Escalation = sigolo.NewEscalation(sigolo.LOG_ERROR, sigolo.LOG_DEBUG, time.Minute, 10*time.Minute)
+func NewEscalation(triggerLevel Level, targetLevel Level, duration time.Duration, cooldown time.Duration) *Escalation {
+	return &Escalation{
+		TriggerLevel: triggerLevel,
+		TargetLevel:  targetLevel,
+		Duration:     duration,
+		Cooldown:     cooldown,
+	}
+}
+
+// SetDefaultEscalation sets the escalation of the default logger, see NewEscalation for details. Use nil to disable it.
+func SetDefaultEscalation(e *Escalation) {
+	escalation = e
+	DefaultLogger = GetLoggerWithCurrentDefaults()
+}
+
+// enables returns true when the escalation is active and entries of the given level are logged because of it.
+func (e *Escalation) enables(logger *Logger, level Level) bool {
+	if level < e.TargetLevel || !e.active.Load() {
+		return false
+	}
+	e.expire()
+	return e.active.Load()
+}
+
+func (e *Escalation) now() time.Time {
+	if e.Clock == nil {
+		return time.Now()
+	}
+	return e.Clock.Now()
+}
+
+// expire ends the escalation when its duration has passed. It doesn't log anything, as it's also called when checking
+// the level, the end is announced by the next written entry, see announceEnd.
+func (e *Escalation) expire() {
+	if !e.active.Load() {
+		return
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if !e.active.Load() || e.now().Before(e.until) {
+		return
+	}
+	e.active.Store(false)
+	e.cooldownUntil = e.until.Add(e.Cooldown)
+	e.ended.Store(true)
+}
+
+// announceEnd ends an expired escalation and announces the end with the caller and trace ID of the entry about to be
+// written, as it happens in the context of that entry.
+func (e *Escalation) announceEnd(logger *Logger, now time.Time, caller string, traceId TraceId) {
+	e.expire()
+	if !e.ended.Load() || !e.ended.CompareAndSwap(true, false) {
+		return
+	}
+	e.announce(logger, now, caller, traceId, fmt.Sprintf("Escalation of log level to %s ended", e.TargetLevel))
+}
+
+// start begins the escalation unless it's already active or cooling down.
+func (e *Escalation) start(logger *Logger, now time.Time, level Level, caller string, traceId TraceId) {
+	e.mutex.Lock()
+	escalationTime := e.now()
+	if e.active.Load() || escalationTime.Before(e.cooldownUntil) {
+		e.mutex.Unlock()
+		return
+	}
+	e.until = escalationTime.Add(e.Duration)
+	e.active.Store(true)
+	e.mutex.Unlock()
+
+	e.announce(logger, now, caller, traceId, fmt.Sprintf("Escalating log level to %s for %s after %s", e.TargetLevel, e.Duration, level))
+}
+
+// announce writes the message regardless of the log level and without the escalation, so that the line itself doesn't
+// start or end an escalation.
+func (e *Escalation) announce(logger *Logger, now time.Time, caller string, traceId TraceId, message string) {
+	announcer := *logger
+	announcer.Escalation = nil
+	announcer.logCallerAt(now, LOG_INFO, caller, traceId, message)
+}
@@ -0,0 +1,134 @@
+package sigolo
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEscalation(t *testing.T) {
+	logger, buffer := newBufferLogger(LOG_INFO)
+	clock := NewFakeClock(time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC))
+	logger.Clock = clock
+	logger.Escalation = NewEscalation(LOG_ERROR, LOG_DEBUG, time.Minute, 10*time.Minute)
+	logger.Escalation.Clock = clock
+
+	logger.Debug("hidden 1")
+	logger.Error("failure")
+	logger.Debug("visible 1")
+	logger.Trace("hidden 2")
+	assertTrue(t, logger.ShouldLog(LOG_DEBUG))
+
+	clock.Advance(time.Minute)
+	written := buffer.Len()
+	logger.Debug("hidden 3")
+	assertFalse(t, logger.ShouldLog(LOG_DEBUG))
+	// The end is announced by the next written entry, not by checking the level.
+	assertTrue(t, buffer.Len() == written)
+
+	// Within the cooldown
+	clock.Advance(5 * time.Minute)
+	logger.Error("failure")
+	logger.Debug("hidden 4")
+
+	clock.Advance(5 * time.Minute)
+	logger.Warn("no trigger")
+	logger.Debug("hidden 5")
+	logger.Error("failure")
+	logger.Debug("visible 2")
+
+	lines := strings.Split(strings.TrimSuffix(buffer.String(), "\n"), "\n")
+	expected := []string{
+		"| failure",
+		"| Escalating log level to DEBUG for 1m0s after ERROR",
+		"| visible 1",
+		"| Escalation of log level to DEBUG ended",
+		"| failure",
+		"| no trigger",
+		"| failure",
+		"| Escalating log level to DEBUG for 1m0s after ERROR",
+		"| visible 2",
+	}
+	if len(lines) != len(expected) {
+		t.Fatalf("Expected %d lines but got %q", len(expected), buffer.String())
+	}
+	for i := range expected {
+		assertTrue(t, strings.HasSuffix(lines[i], expected[i]))
+	}
+	assertTrue(t, strings.Contains(lines[1], "[INFO]"))
+	assertTrue(t, strings.Contains(lines[1], "escalation_test.go"))
+	// The end is announced with the caller of the entry writing it, not the one which started the escalation.
+	assertTrue(t, callerOf(lines[3]) == callerOf(lines[4]))
+	assertTrue(t, callerOf(lines[3]) != callerOf(lines[0]))
+}
+
+// callerOf returns the part between the level and the message of a line written with the LogDefault format.
+func callerOf(line string) string {
+	line = line[strings.Index(line, "]")+1:]
+	return strings.TrimSpace(line[:strings.Index(line, "|")])
+}
+
+func TestEscalationUsesOwnClock(t *testing.T) {
+	logger, buffer := newBufferLogger(LOG_INFO)
+	logger.Clock = NewFakeClock(time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC))
+	clock := NewFakeClock(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC))
+	logger.Escalation = NewEscalation(LOG_ERROR, LOG_DEBUG, time.Minute, 0)
+	logger.Escalation.Clock = clock
+	other := *logger
+	other.Clock = nil
+
+	logger.Error("failure")
+	// The clock of the other logger is long after the end of the escalation by the clock of the first one.
+	other.Debug("visible")
+	clock.Advance(time.Minute)
+	other.Debug("hidden")
+
+	assertTrue(t, strings.Contains(buffer.String(), "| visible\n"))
+	assertFalse(t, strings.Contains(buffer.String(), "hidden"))
+}
+
+func TestEscalationIgnoresBufferedEntries(t *testing.T) {
+	logger, buffer := newBufferedTestLogger(10)
+	logger.Escalation = NewEscalation(LOG_ERROR, LOG_DEBUG, time.Hour, 0)
+
+	logger.Error("failure")
+	assertFalse(t, logger.Escalation.active.Load())
+
+	logger.Commit()
+	assertTrue(t, strings.HasSuffix(buffer.String(), "| failure\n"))
+	assertFalse(t, strings.Contains(buffer.String(), "Escalating"))
+}
+
+func TestEscalationIsSharedByCopies(t *testing.T) {
+	logger, buffer := newBufferLogger(LOG_WARN)
+	logger.Escalation = NewEscalation(LOG_WARN, LOG_TRACE, time.Hour, 0)
+	other := *logger
+
+	other.Warn("foo")
+	logger.Trace("bar")
+
+	assertTrue(t, strings.Count(buffer.String(), "\n") == 3)
+	assertTrue(t, strings.HasSuffix(buffer.String(), "| bar\n"))
+}
+
+func TestDefaultEscalation(t *testing.T) {
+	buffer := prepareBuffer(t, LOG_DEBUG)
+	SetDefaultLogLevel(LOG_INFO)
+	SetDefaultEscalation(NewEscalation(LOG_ERROR, LOG_DEBUG, time.Hour, 0))
+	t.Cleanup(func() {
+		SetDefaultEscalation(nil)
+	})
+
+	assertFalse(t, ShouldLogDebug())
+	Debug("hidden")
+	levelOutputs[LOG_ERROR] = buffer
+	defer func() {
+		levelOutputs[LOG_ERROR] = DefaultLevelOutputs()[LOG_ERROR]
+	}()
+	Error("failure")
+	assertTrue(t, ShouldLogDebug())
+	Debug("visible")
+
+	assertFalse(t, strings.Contains(buffer.String(), "hidden"))
+	assertTrue(t, strings.HasSuffix(buffer.String(), "| visible\n"))
+}
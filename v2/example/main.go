@@ -82,6 +82,6 @@ func main() {
 	sigolo.FatalCheck(thisFunc())
 }
 
-func simpleInfo(writer io.Writer, time, level string, maxLength int, caller string, traceId int, message string) {
-	fmt.Fprintf(writer, ">>  My custom Infof  ||  %s\n", message)
+func simpleInfo(writer io.Writer, entry sigolo.FormattedEntry) {
+	fmt.Fprintf(writer, ">>  My custom Infof  ||  %s\n", entry.Message)
 }
@@ -0,0 +1,136 @@
+package sigolo
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Field is additional information of an entry. Fields are printed as "key=value" pairs after the message.
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// Hook is called for each entry of the levels it's registered for, before the entry is formatted. A hook may change the
+// entry, e.g. add fields, or perform side effects like counting entries. Returning false vetoes the entry, which is then
+// neither passed to further hooks nor written.
+//
+// Hooks are called in the order they were added. When a hook panics, the panic is logged and the entry is passed on as
+// if the hook returned true.
+type Hook func(entry *Entry) bool
+
+// Observer is called for each entry right before it's written, i.e. after hooks, redaction, deduplication and
+// buffering. Unlike hooks, observers must neither change the entry nor keep a reference to it.
+type Observer func(entry *Entry)
+
+// AddObserver registers the observer. Like hooks, observers should be added before the logger is used concurrently.
+// Copies of the logger, e.g. spans, keep the observers they had when they were created.
+func (l *Logger) AddObserver(observer Observer) {
+	// Limit the capacity, so that append copies the slice instead of writing into an array shared with copies.
+	l.Observers = append(l.Observers[:len(l.Observers):len(l.Observers)], observer)
+}
+
+// AddHook registers the hook for the given levels or for all levels when no level is given. Hooks should be added before
+// the logger is used concurrently. Copies of the logger, e.g. spans, keep the hooks they had when they were created.
+func (l *Logger) AddHook(hook Hook, levels ...Level) {
+	l.Hooks = addHook(l.Hooks, hook, levels)
+}
+
+// AddDefaultHook registers the hook on the default logger, see Logger.AddHook for details.
+func AddDefaultHook(hook Hook, levels ...Level) {
+	hooks = addHook(hooks, hook, levels)
+	DefaultLogger = GetLoggerWithCurrentDefaults()
+}
+
+// addHook returns a copy of the hooks with the given hook added. The map is shared between copies of a logger, so it's
+// never changed in place.
+func addHook(hooks map[Level][]Hook, hook Hook, levels []Level) map[Level][]Hook {
+	if len(levels) == 0 {
+		levels = []Level{LOG_PLAIN, LOG_TRACE, LOG_DEBUG, LOG_INFO, LOG_WARN, LOG_ERROR, LOG_FATAL}
+	}
+
+	copied := make(map[Level][]Hook, len(hooks)+len(levels))
+	for level, levelHooks := range hooks {
+		copied[level] = levelHooks
+	}
+	for _, level := range levels {
+		levelHooks := copied[level]
+		// Limit the capacity, so that append copies the slice instead of writing into the shared array.
+		copied[level] = append(levelHooks[:len(levelHooks):len(levelHooks)], hook)
+	}
+	return copied
+}
+
+// FieldHook returns a hook adding the given field to every entry, e.g. FieldHook("pid", os.Getpid()).
+func FieldHook(key string, value interface{}) Hook {
+	return func(entry *Entry) bool {
+		entry.Fields = append(entry.Fields, Field{Key: key, Value: value})
+		return true
+	}
+}
+
+// HostnameHook returns a hook adding the hostname as field "host" to every entry.
+func HostnameHook() Hook {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "???"
+	}
+	return FieldHook("host", hostname)
+}
+
+// runHooks calls all hooks registered for the level of the entry and returns false when one of them vetoed the entry.
+func (l *Logger) runHooks(entry *Entry) bool {
+	for _, hook := range l.Hooks[entry.Level] {
+		if !l.runHook(hook, entry) {
+			return false
+		}
+	}
+	return true
+}
+
+func (l *Logger) runHook(hook Hook, entry *Entry) (keep bool) {
+	defer func() {
+		if err := recover(); err != nil {
+			// Write directly, so that hooks are not called again for this entry.
+			l.write(&Entry{
+				Time:    entry.Time,
+				Level:   LOG_ERROR,
+				Caller:  entry.Caller,
+				TraceId: entry.TraceId,
+				SpanId:  entry.SpanId,
+				Message: fmt.Sprintf("Hook panicked: %v", err),
+			})
+			keep = true
+		}
+	}()
+	return hook(entry)
+}
+
+// messageWithFields returns the sanitized message followed by the fields of the entry, e.g. "message key=value foo=bar".
+// Quoted field values are already escaped and therefore not sanitized again.
+func messageWithFields(entry *Entry, mode Sanitization) string {
+	builder := strings.Builder{}
+	builder.WriteString(sanitize(entry.Message, mode))
+	for _, field := range entry.Fields {
+		builder.WriteByte(' ')
+		builder.WriteString(sanitize(field.Key, mode))
+		builder.WriteByte('=')
+		value := formatFieldValue(field.Value)
+		if !strings.HasPrefix(value, `"`) {
+			value = sanitize(value, mode)
+		}
+		builder.WriteString(value)
+	}
+	return builder.String()
+}
+
+// formatFieldValue formats the value and quotes it when it contains spaces, quotes, control characters or is empty.
+func formatFieldValue(value interface{}) string {
+	text := fmt.Sprint(value)
+	if text == "" || strings.ContainsAny(text, " \t\"=") || needsSanitization(text) {
+		return strconv.Quote(text)
+	}
+	return text
+}
@@ -0,0 +1,103 @@
+package sigolo
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHookAddsFields(t *testing.T) {
+	logger, buffer := newBufferLogger(LOG_INFO)
+	logger.AddHook(FieldHook("pid", 123))
+	logger.AddHook(FieldHook("name", "foo bar"), LOG_ERROR)
+
+	logger.Info("info")
+	logger.Error("error")
+
+	lines := strings.Split(strings.TrimSuffix(buffer.String(), "\n"), "\n")
+	assertTrue(t, strings.HasSuffix(lines[0], "| info pid=123"))
+	assertTrue(t, strings.HasSuffix(lines[1], `| error pid=123 name="foo bar"`))
+}
+
+func TestHookOrderAndVeto(t *testing.T) {
+	logger, buffer := newBufferLogger(LOG_INFO)
+	var calls []string
+	logger.AddHook(func(entry *Entry) bool {
+		calls = append(calls, "first")
+		entry.Message = strings.ToUpper(entry.Message)
+		return !strings.Contains(entry.Message, "SECRET")
+	})
+	logger.AddHook(func(entry *Entry) bool {
+		calls = append(calls, "second")
+		return true
+	})
+
+	logger.Info("foo")
+	logger.Info("secret")
+
+	assertTrue(t, strings.Join(calls, ",") == "first,second,first")
+	assertTrue(t, strings.Count(buffer.String(), "\n") == 1)
+	assertTrue(t, strings.HasSuffix(buffer.String(), "| FOO\n"))
+}
+
+func TestHookPanicIsIsolated(t *testing.T) {
+	logger, buffer := newBufferLogger(LOG_INFO)
+	logger.AddHook(func(entry *Entry) bool {
+		panic("boom")
+	})
+	logger.AddHook(FieldHook("after", "panic"))
+
+	logger.Info("foo")
+
+	lines := strings.Split(strings.TrimSuffix(buffer.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines but got %d: %q", len(lines), buffer.String())
+	}
+	assertTrue(t, strings.Contains(lines[0], "[ERROR]"))
+	assertTrue(t, strings.HasSuffix(lines[0], "| Hook panicked: boom"))
+	assertTrue(t, strings.HasSuffix(lines[1], "| foo after=panic"))
+}
+
+func TestDefaultHook(t *testing.T) {
+	buffer := prepareBuffer(t, LOG_WARN)
+	defer func() {
+		hooks = map[Level][]Hook{}
+		SetDefaultLogLevel(LOG_INFO)
+	}()
+
+	AddDefaultHook(FieldHook("foo", "bar"), LOG_WARN)
+	Warn("warning")
+
+	assertTrue(t, strings.HasSuffix(buffer.String(), "| warning foo=bar\n"))
+}
+
+func TestAddHookDoesNotChangeCopies(t *testing.T) {
+	logger, buffer := newBufferLogger(LOG_INFO)
+	logger.AddHook(FieldHook("pid", 123))
+	child := *logger
+
+	child.AddHook(FieldHook("child", true))
+	logger.Info("parent")
+	child.Info("child")
+
+	lines := strings.Split(strings.TrimSuffix(buffer.String(), "\n"), "\n")
+	assertTrue(t, strings.HasSuffix(lines[0], "| parent pid=123"))
+	assertTrue(t, strings.HasSuffix(lines[1], "| child pid=123 child=true"))
+}
+
+func TestObserverSeesWrittenEntries(t *testing.T) {
+	logger, _ := newBufferLogger(LOG_INFO)
+	var observed []string
+	logger.AddObserver(func(entry *Entry) {
+		observed = append(observed, messageWithFields(entry, SANITIZE_NONE))
+	})
+	logger.AddHook(FieldHook("pid", 123))
+	logger.AddHook(func(entry *Entry) bool {
+		return entry.Message != "vetoed"
+	})
+
+	logger.Info("foo")
+	logger.Info("vetoed")
+	logger.Debug("disabled")
+
+	assertTrue(t, strings.Join(observed, ",") == "foo pid=123")
+}
@@ -0,0 +1,215 @@
+package sigolo
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// LoggingTransport is an http.RoundTripper logging each request with its method, URL, response status, response size
+// and latency. The logger is taken from the request context (see ContextWithLogger) and falls back to Logger.
+//
+// When the logger has the TRACE level, the headers and, if enabled, the bodies of requests and responses are logged as
+// well. Values of sensitive headers are replaced and the Redactor of the logger is applied to the dumps.
+//
+// The line of a request is logged when the response body has been read completely or closed, as the size is only known
+// then. A body that's neither read to the end nor closed therefore leaks the connection and also loses the line.
+// Responses with status 101 Switching Protocols are logged right away and their body, which is the connection to
+// write to, is passed on unchanged.
+type LoggingTransport struct {
+	// Transport performs the actual requests. When nil, http.DefaultTransport is used.
+	Transport http.RoundTripper
+	// Logger is used for requests without logger in their context. When nil, the DefaultLogger is used.
+	Logger *Logger
+	// Level of the line logged for each request, NewLoggingTransport uses DEBUG. Failed requests are logged as ERROR.
+	Level Level
+	// DumpBodies enables logging the request and response bodies at TRACE level.
+	DumpBodies bool
+	// MaxDumpSize is the maximum number of bytes of a body that's logged.
+	MaxDumpSize int
+	// RedactedHeaders are the headers whose values are replaced by RedactionReplacement in dumps.
+	RedactedHeaders []string
+	// TraceIdHeader is set to the trace ID of the logger on each request. An empty string disables this.
+	TraceIdHeader string
+	// PropagateTraceContext sets the W3C traceparent and tracestate headers, see Logger.InjectTraceContext.
+	PropagateTraceContext bool
+}
+
+// NewLoggingTransport wraps the given transport, which may be nil to use http.DefaultTransport. Requests are logged
+// at DEBUG level and the trace ID is propagated as "X-Trace-Id" header and as W3C trace context.
+func NewLoggingTransport(transport http.RoundTripper) *LoggingTransport {
+	return &LoggingTransport{
+		Transport:             transport,
+		Level:                 LOG_DEBUG,
+		MaxDumpSize:           4 * 1024,
+		RedactedHeaders:       []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"},
+		TraceIdHeader:         "X-Trace-Id",
+		PropagateTraceContext: true,
+	}
+}
+
+func (t *LoggingTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	logger := t.logger(request)
+	// The actual caller is the function calling e.g. http.Client.Do.
+	caller := getCallerDetailsSkipping(1, logger.CallerFormat, "net/http.", sigoloFunctionPrefix+"(*LoggingTransport).")
+
+	// A RoundTripper must not modify the given request, neither its headers nor its body when dumping it.
+	request = request.Clone(request.Context())
+	if request.Header == nil {
+		request.Header = make(http.Header)
+	}
+	if t.TraceIdHeader != "" {
+		request.Header.Set(t.TraceIdHeader, logger.LogTraceId.String())
+	}
+	if t.PropagateTraceContext {
+		logger.InjectTraceContext(request)
+	}
+
+	dump := logger.ShouldLog(LOG_TRACE)
+	if dump {
+		t.dumpRequest(logger, caller, request)
+	}
+
+	transport := t.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	start := logger.now()
+	response, err := transport.RoundTrip(request)
+	if err != nil {
+		logLine(logger, LOG_ERROR, caller, "%s %s failed after %s: %v", request.Method, request.URL.Redacted(), logger.now().Sub(start), err)
+		return response, err
+	}
+
+	if dump {
+		t.dumpResponse(logger, caller, response)
+	}
+
+	if response.StatusCode == http.StatusSwitchingProtocols {
+		// The body is the upgraded connection, e.g. of a websocket, and must stay writable.
+		logLine(logger, t.Level, caller, "%s %s: %s after %s", request.Method, request.URL.Redacted(), response.Status, logger.now().Sub(start))
+		return response, nil
+	}
+
+	// The size is known when the body has been read completely or closed.
+	response.Body = &loggingBody{
+		body: response.Body,
+		done: func(size int64) {
+			logLine(logger, t.Level, caller, "%s %s: %s, %d bytes in %s", request.Method, request.URL.Redacted(), response.Status, size, logger.now().Sub(start))
+		},
+	}
+	return response, nil
+}
+
+// logger returns the logger of the request context or the configured one. Otherwise, the DefaultLogger is copied with
+// a new trace ID, so that all lines of a request have the same trace ID.
+func (t *LoggingTransport) logger(request *http.Request) *Logger {
+	if logger := LoggerFromContext(request.Context()); logger != nil {
+		return logger
+	}
+	if t.Logger != nil {
+		return t.Logger
+	}
+	logger := *DefaultLogger
+	logger.LogTraceId = newTraceId()
+	return &logger
+}
+
+// logLine formats and logs the message, unless the entry isn't written anyway.
+func logLine(logger *Logger, level Level, caller string, format string, args ...interface{}) {
+	if logger.shouldLogSampled(level) {
+		logger.logCaller(level, caller, logger.LogTraceId, fmt.Sprintf(format, args...))
+	}
+}
+
+func (t *LoggingTransport) dumpRequest(logger *Logger, caller string, request *http.Request) {
+	if !logger.shouldLogSampled(LOG_TRACE) {
+		return
+	}
+	message := fmt.Sprintf("Request %s %s\n%s", request.Method, request.URL.Redacted(), t.formatHeaders(request.Header))
+	if t.DumpBodies && request.Body != nil && request.Body != http.NoBody {
+		var body []byte
+		body, request.Body = t.peekBody(request.Body)
+		message += "\n" + t.formatBody(body)
+	}
+	logger.logCaller(LOG_TRACE, caller, logger.LogTraceId, message)
+}
+
+func (t *LoggingTransport) dumpResponse(logger *Logger, caller string, response *http.Response) {
+	if !logger.shouldLogSampled(LOG_TRACE) {
+		return
+	}
+	message := fmt.Sprintf("Response %s\n%s", response.Status, t.formatHeaders(response.Header))
+	if t.DumpBodies && response.Body != nil && response.Body != http.NoBody && response.StatusCode != http.StatusSwitchingProtocols {
+		var body []byte
+		body, response.Body = t.peekBody(response.Body)
+		message += "\n" + t.formatBody(body)
+	}
+	logger.logCaller(LOG_TRACE, caller, logger.LogTraceId, message)
+}
+
+// formatHeaders returns the sorted headers, one per line, with the values of redacted headers replaced.
+func (t *LoggingTransport) formatHeaders(header http.Header) string {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	lines := make([]string, 0, len(names))
+	for _, name := range names {
+		value := strings.Join(header[name], ", ")
+		for _, redacted := range t.RedactedHeaders {
+			if strings.EqualFold(name, redacted) {
+				value = RedactionReplacement
+			}
+		}
+		lines = append(lines, name+": "+value)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// peekBody reads up to MaxDumpSize+1 bytes of the body and returns them together with a body that still contains all
+// data.
+func (t *LoggingTransport) peekBody(body io.ReadCloser) ([]byte, io.ReadCloser) {
+	peeked, _ := io.ReadAll(io.LimitReader(body, int64(t.MaxDumpSize)+1))
+	return peeked, struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(peeked), body), body}
+}
+
+func (t *LoggingTransport) formatBody(body []byte) string {
+	if len(body) > t.MaxDumpSize {
+		return fmt.Sprintf("%s... (truncated after %d bytes)", body[:t.MaxDumpSize], t.MaxDumpSize)
+	}
+	return string(body)
+}
+
+// loggingBody counts the bytes read from the body and calls done once when the body is read completely or closed.
+type loggingBody struct {
+	body io.ReadCloser
+	size int64
+	once sync.Once
+	done func(size int64)
+}
+
+func (b *loggingBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	b.size += int64(n)
+	if err == io.EOF {
+		b.once.Do(func() { b.done(b.size) })
+	}
+	return n, err
+}
+
+func (b *loggingBody) Close() error {
+	err := b.body.Close()
+	b.once.Do(func() { b.done(b.size) })
+	return err
+}
@@ -0,0 +1,206 @@
+package sigolo
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func newTestServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Set-Cookie", "session=secret")
+		w.Header().Set("X-Received-Trace-Id", r.Header.Get("X-Trace-Id"))
+		w.Header().Set("X-Received-Traceparent", r.Header.Get(TraceparentHeader))
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("response to " + string(body)))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestLoggingTransport(t *testing.T) {
+	server := newTestServer(t)
+	logger, buffer := newBufferLogger(LOG_DEBUG)
+	logger.LogTraceId = SequentialTraceId(0x2a)
+	transport := NewLoggingTransport(nil)
+	transport.Logger = logger
+	client := &http.Client{Transport: transport}
+
+	response, err := client.Post(server.URL+"/foo", "text/plain", strings.NewReader("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(response.Body)
+	response.Body.Close()
+
+	assertTrue(t, string(body) == "response to hello")
+	assertTrue(t, response.Header.Get("X-Received-Trace-Id") == "2a")
+	assertTrue(t, strings.HasPrefix(response.Header.Get("X-Received-Traceparent"), "00-0000000000000000000000000000002a-"))
+
+	output := buffer.String()
+	assertTrue(t, strings.Count(output, "\n") == 1)
+	assertTrue(t, strings.Contains(output, "[DEBUG]"))
+	assertTrue(t, strings.Contains(output, "httpclient_test.go"))
+	assertTrue(t, strings.Contains(output, "| POST "+server.URL+"/foo: 201 Created, 17 bytes in "))
+}
+
+func TestLoggingTransportUsesLoggerOfContext(t *testing.T) {
+	server := newTestServer(t)
+	logger, buffer := newBufferLogger(LOG_DEBUG)
+	logger.LogTraceId = SequentialTraceId(0x2b)
+	client := &http.Client{Transport: NewLoggingTransport(nil)}
+
+	request, _ := http.NewRequestWithContext(ContextWithLogger(context.Background(), logger), http.MethodGet, server.URL, nil)
+	response, err := client.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+
+	assertTrue(t, strings.Contains(buffer.String(), "| #2b | GET "))
+	assertTrue(t, LoggerFromContext(context.Background()) == nil)
+}
+
+func TestLoggingTransportDumps(t *testing.T) {
+	server := newTestServer(t)
+	logger, buffer := newBufferLogger(LOG_TRACE)
+	transport := NewLoggingTransport(nil)
+	transport.Logger = logger
+	transport.DumpBodies = true
+	transport.MaxDumpSize = 8
+	client := &http.Client{Transport: transport}
+
+	request, _ := http.NewRequest(http.MethodPost, server.URL, strings.NewReader("hello world"))
+	request.Header.Set("Authorization", "Bearer foo")
+	response, err := client.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(response.Body)
+	response.Body.Close()
+
+	output := buffer.String()
+	assertTrue(t, string(body) == "response to hello world")
+	assertTrue(t, strings.Contains(output, "[TRACE]"))
+	assertTrue(t, strings.Contains(output, "\nAuthorization: "+RedactionReplacement+"\n"))
+	assertTrue(t, strings.Contains(output, "\nSet-Cookie: "+RedactionReplacement+"\n"))
+	assertTrue(t, strings.Contains(output, "\nhello wo... (truncated after 8 bytes)\n"))
+	assertTrue(t, strings.Contains(output, "\nresponse... (truncated after 8 bytes)\n"))
+	assertFalse(t, strings.Contains(output, "Bearer foo"))
+}
+
+func TestLoggingTransportError(t *testing.T) {
+	logger, buffer := newBufferLogger(LOG_INFO)
+	transport := NewLoggingTransport(nil)
+	transport.Logger = logger
+	client := &http.Client{Transport: transport}
+
+	_, err := client.Get("http://127.0.0.1:1/")
+
+	assertTrue(t, err != nil)
+	assertTrue(t, strings.Contains(buffer.String(), "[ERROR]"))
+	assertTrue(t, strings.Contains(buffer.String(), "| GET http://127.0.0.1:1/ failed after "))
+}
+
+func TestLoggingTransportDoesNotChangeRequest(t *testing.T) {
+	server := newTestServer(t)
+	logger, _ := newBufferLogger(LOG_TRACE)
+	transport := NewLoggingTransport(nil)
+	transport.Logger = logger
+	transport.DumpBodies = true
+	transport.TraceIdHeader = ""
+	transport.PropagateTraceContext = false
+	client := &http.Client{Transport: transport}
+
+	request, _ := http.NewRequest(http.MethodPost, server.URL, strings.NewReader("hello"))
+	body := request.Body
+	response, err := client.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+
+	assertTrue(t, request.Body == body)
+}
+
+func TestLoggingTransportRequestWithoutHeader(t *testing.T) {
+	server := newTestServer(t)
+	logger, buffer := newBufferLogger(LOG_DEBUG)
+	transport := NewLoggingTransport(nil)
+	transport.Logger = logger
+
+	serverUrl, _ := url.Parse(server.URL)
+	response, err := transport.RoundTrip(&http.Request{Method: http.MethodGet, URL: serverUrl})
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+
+	assertTrue(t, strings.Contains(buffer.String(), "| GET "+server.URL+": 201 Created"))
+}
+
+// upgradeTransport returns a 101 response whose body is the upgraded connection.
+type upgradeTransport struct {
+	connection io.ReadWriteCloser
+}
+
+func (t upgradeTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	return &http.Response{
+		Status:     "101 Switching Protocols",
+		StatusCode: http.StatusSwitchingProtocols,
+		Header:     http.Header{},
+		Body:       t.connection,
+		Request:    request,
+	}, nil
+}
+
+type fakeConnection struct {
+	io.Reader
+	io.Writer
+}
+
+func (c fakeConnection) Close() error {
+	return nil
+}
+
+func TestLoggingTransportSwitchingProtocols(t *testing.T) {
+	logger, buffer := newBufferLogger(LOG_TRACE)
+	connection := fakeConnection{Reader: strings.NewReader(""), Writer: &strings.Builder{}}
+	transport := NewLoggingTransport(upgradeTransport{connection: connection})
+	transport.Logger = logger
+	transport.DumpBodies = true
+
+	request, _ := http.NewRequest(http.MethodGet, "http://example.com/socket", nil)
+	response, err := transport.RoundTrip(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, writable := response.Body.(io.Writer)
+	assertTrue(t, writable)
+	assertTrue(t, strings.Contains(buffer.String(), "| GET http://example.com/socket: 101 Switching Protocols after "))
+}
+
+func TestLoggingTransportUsesNewTraceIdForDefaultLogger(t *testing.T) {
+	server := newTestServer(t)
+	buffer := prepareBuffer(t, LOG_DEBUG)
+	SetDefaultFormatFunction(LOG_DEBUG, LogDefault)
+	defer SetDefaultFormatFunction(LOG_DEBUG, LogDefaultStatic)
+	defaultTraceId := DefaultLogger.LogTraceId
+	client := &http.Client{Transport: NewLoggingTransport(nil)}
+
+	response, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+
+	assertTrue(t, DefaultLogger.LogTraceId == defaultTraceId)
+	assertTrue(t, response.Header.Get("X-Received-Trace-Id") != defaultTraceId.String())
+	assertTrue(t, strings.Contains(buffer.String(), "| #"+response.Header.Get("X-Received-Trace-Id")+" | GET "))
+}
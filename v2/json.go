@@ -0,0 +1,88 @@
+package sigolo
+
+import (
+	"io"
+	"unicode/utf8"
+)
+
+// LogJson prints an entry as one JSON object per line, e.g.
+//
+//	{"time":"2024-03-01 12:30:00.000","level":"INFO","caller":"main.go:42","trace_id":"2a","span_id":"00f067aa0ba902b7","message":"foo"}
+//
+// The span ID is omitted when there's none. Fields of the entry are part of the message. As JSON strings are always
+// escaped, the message isn't sanitized again.
+func LogJson(writer io.Writer, entry FormattedEntry) {
+	buffer := getBuffer()
+	*buffer = append(*buffer, `{"time":`...)
+	*buffer = appendJsonString(*buffer, entry.Time)
+	*buffer = append(*buffer, `,"level":`...)
+	*buffer = appendJsonString(*buffer, entry.Level.String())
+	*buffer = append(*buffer, `,"caller":`...)
+	*buffer = appendJsonString(*buffer, entry.Caller)
+	*buffer = append(*buffer, `,"trace_id":"`...)
+	*buffer = entry.TraceId.Append(*buffer)
+	*buffer = append(*buffer, '"')
+	if entry.SpanId != 0 {
+		*buffer = append(*buffer, `,"span_id":"`...)
+		*buffer = entry.SpanId.Append(*buffer)
+		*buffer = append(*buffer, '"')
+	}
+	*buffer = append(*buffer, `,"message":`...)
+	*buffer = appendJsonString(*buffer, entry.RawMessage)
+	*buffer = append(*buffer, "}\n"...)
+	writer.Write(*buffer)
+	putBuffer(buffer)
+}
+
+// appendJsonString appends the text as quoted JSON string. Like encoding/json, invalid UTF-8 is replaced by U+FFFD and
+// the line and paragraph separators are escaped, as some JavaScript parsers don't accept them within strings.
+func appendJsonString(buffer []byte, text string) []byte {
+	const hexDigits = "0123456789abcdef"
+
+	buffer = append(buffer, '"')
+	for i := 0; i < len(text); {
+		c := text[i]
+		if c >= 0x20 && c != '"' && c != '\\' && c < utf8.RuneSelf {
+			buffer = append(buffer, c)
+			i++
+			continue
+		}
+
+		switch c {
+		case '"', '\\':
+			buffer = append(buffer, '\\', c)
+			i++
+			continue
+		case '\n':
+			buffer = append(buffer, '\\', 'n')
+			i++
+			continue
+		case '\r':
+			buffer = append(buffer, '\\', 'r')
+			i++
+			continue
+		case '\t':
+			buffer = append(buffer, '\\', 't')
+			i++
+			continue
+		}
+		if c < 0x20 {
+			buffer = append(buffer, '\\', 'u', '0', '0', hexDigits[c>>4], hexDigits[c&0xf])
+			i++
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(text[i:])
+		switch {
+		case r == utf8.RuneError && size == 1:
+			buffer = append(buffer, `\ufffd`...)
+		case r == '\u2028' || r == '\u2029':
+			buffer = append(buffer, `\u202`...)
+			buffer = append(buffer, hexDigits[r&0xf])
+		default:
+			buffer = append(buffer, text[i:i+size]...)
+		}
+		i += size
+	}
+	return append(buffer, '"')
+}
@@ -0,0 +1,58 @@
+package sigolo
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestLogJson(t *testing.T) {
+	logger, buffer := newBufferLogger(LOG_INFO)
+	logger.FormatFunctions[LOG_WARN] = LogJson
+	logger.LogTraceId = SequentialTraceId(0x2a)
+	logger.LogSpanId = 0x00f067aa0ba902b7
+
+	logger.Warn("quote \" backslash \\ newline \n tab \t bell \a invalid \xff separator \u2028 umlaut ä")
+
+	var entry map[string]string
+	if err := json.Unmarshal(buffer.Bytes(), &entry); err != nil {
+		t.Fatalf("Invalid JSON %q: %v", buffer.String(), err)
+	}
+	assertTrue(t, strings.Count(buffer.String(), "\n") == 1)
+	assertTrue(t, entry["level"] == "WARN")
+	assertTrue(t, strings.HasPrefix(entry["caller"], "json_test.go:"))
+	assertTrue(t, entry["trace_id"] == "2a")
+	assertTrue(t, entry["span_id"] == "00f067aa0ba902b7")
+	assertTrue(t, entry["message"] == "quote \" backslash \\ newline \n tab \t bell \a invalid \ufffd separator \u2028 umlaut ä")
+	assertTrue(t, strings.Contains(buffer.String(), `separator \u2028 umlaut ä"`))
+}
+
+func TestLogJsonWithoutSpan(t *testing.T) {
+	buffer := &strings.Builder{}
+
+	LogJson(buffer, FormattedEntry{
+		Time:        "time",
+		Level:       LOG_INFO,
+		LevelString: "[INFO] ",
+		Caller:      "foo.go:1",
+		TraceId:     SequentialTraceId(1),
+		Message:     "foo",
+		RawMessage:  "foo",
+	})
+
+	assertTrue(t, buffer.String() == `{"time":"time","level":"INFO","caller":"foo.go:1","trace_id":"1","message":"foo"}`+"\n")
+}
+
+func TestLogJsonIgnoresLevelStrings(t *testing.T) {
+	logger, buffer := newBufferLogger(LOG_PLAIN)
+	logger.FormatFunctions[LOG_PLAIN] = LogJson
+	logger.FormatFunctions[LOG_INFO] = LogJson
+	logger.LevelStrings[LOG_INFO] = "ℹ️ "
+
+	logger.Plain("foo")
+	logger.Info("bar")
+
+	lines := strings.Split(strings.TrimSuffix(buffer.String(), "\n"), "\n")
+	assertTrue(t, strings.Contains(lines[0], `"level":"PLAIN"`))
+	assertTrue(t, strings.Contains(lines[1], `"level":"INFO"`))
+}
@@ -0,0 +1,229 @@
+package sigolo
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultLoggerName is the name under which the LevelHandler shows and changes the level of the DefaultLogger.
+const DefaultLoggerName = "default"
+
+var (
+	namedLoggersMutex sync.Mutex
+	namedLoggers      = map[string]*Logger{}
+)
+
+// RegisterLogger makes the logger available under the given name, e.g. to change its level using the LevelHandler. A
+// logger registered before under the same name is replaced. The DefaultLoggerName and the empty name stand for the
+// DefaultLogger and are therefore rejected.
+func RegisterLogger(name string, logger *Logger) error {
+	if name == "" || name == DefaultLoggerName {
+		return fmt.Errorf("logger name %q is reserved for the default logger", name)
+	}
+
+	namedLoggersMutex.Lock()
+	defer namedLoggersMutex.Unlock()
+	namedLoggers[name] = logger
+	return nil
+}
+
+// UnregisterLogger removes the logger with the given name from the registry.
+func UnregisterLogger(name string) {
+	namedLoggersMutex.Lock()
+	defer namedLoggersMutex.Unlock()
+	delete(namedLoggers, name)
+}
+
+// GetLogger returns the logger registered under the given name or nil if there is none.
+func GetLogger(name string) *Logger {
+	namedLoggersMutex.Lock()
+	defer namedLoggersMutex.Unlock()
+	return namedLoggers[name]
+}
+
+// RegisteredLoggerNames returns the sorted names of all registered loggers.
+func RegisteredLoggerNames() []string {
+	namedLoggersMutex.Lock()
+	defer namedLoggersMutex.Unlock()
+
+	names := make([]string, 0, len(namedLoggers))
+	for name := range namedLoggers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// changeLogLevel sets the level of the logger and logs the change. The line is written with the more verbose one of the
+// old and new level, so that it's visible when either of them enables INFO.
+func (l *Logger) changeLogLevel(name string, level Level) {
+	logLevelChange(name, l.loadLogLevel(), level, l, func() *Logger {
+		l.SetLogLevel(level)
+		return l
+	})
+}
+
+// changeDefaultLogLevel is equal to SetDefaultLogLevel but also logs the change, see Logger.changeLogLevel.
+func changeDefaultLogLevel(level Level) {
+	logLevelChange(DefaultLoggerName, GetCurrentLogLevel(), level, DefaultLogger, func() *Logger {
+		SetDefaultLogLevel(level)
+		return DefaultLogger
+	})
+}
+
+// stepDefaultLogLevel changes the level of the DefaultLogger by the given number of steps, but not beyond TRACE and
+// FATAL.
+func stepDefaultLogLevel(steps int) {
+	level := GetCurrentLogLevel() + Level(steps)
+	if level < LOG_TRACE {
+		level = LOG_TRACE
+	} else if level > LOG_FATAL {
+		level = LOG_FATAL
+	}
+	changeDefaultLogLevel(level)
+}
+
+// logLevelChange calls set, which changes the level and returns the changed logger, and logs the change before or
+// after it, whichever is more verbose.
+func logLevelChange(name string, oldLevel Level, newLevel Level, logger *Logger, set func() *Logger) {
+	if oldLevel == newLevel {
+		return
+	}
+	if newLevel < oldLevel {
+		logger = set()
+	}
+	logger.Infof("Log level of %s logger changed from %s to %s", name, oldLevel, newLevel)
+	if newLevel > oldLevel {
+		set()
+	}
+}
+
+// LevelHandler is an http.Handler showing and changing the levels of the DefaultLogger and all registered loggers:
+//
+//	GET /           lists all loggers with their levels
+//	GET /?logger=db shows the level of the logger "db"
+//	PUT /?logger=db sets the level of the logger "db" to the level in the body, e.g. "DEBUG" or {"level":"DEBUG"}
+//
+// Without the "logger" parameter, PUT changes the DefaultLogger. Responses are JSON when the Accept header of the request
+// asks for it, otherwise plain text with one "name LEVEL" line per logger. Request bodies are JSON when the Content-Type
+// is application/json.
+//
+// The handler has no access control, anyone reaching it can make the application log everything. Don't expose it
+// publicly, serve it only on an internal or admin listener or behind an authenticating handler.
+type LevelHandler struct{}
+
+type levelResponse struct {
+	Name  string `json:"name"`
+	Level string `json:"level"`
+}
+
+type levelRequest struct {
+	Level string `json:"level"`
+}
+
+func (h LevelHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("logger")
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		if name == "" {
+			writeLevels(w, r, allLevels(), false)
+			return
+		}
+	case http.MethodPut:
+		if name == "" {
+			name = DefaultLoggerName
+		}
+		level, err := readLevel(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if name == DefaultLoggerName {
+			changeDefaultLogLevel(level)
+		} else if logger := GetLogger(name); logger != nil {
+			logger.changeLogLevel(name, level)
+		}
+	default:
+		w.Header().Set("Allow", "GET, HEAD, PUT")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	level, ok := levelOf(name)
+	if !ok {
+		http.Error(w, fmt.Sprintf("unknown logger %q", name), http.StatusNotFound)
+		return
+	}
+	writeLevels(w, r, []levelResponse{{Name: name, Level: level.String()}}, true)
+}
+
+// allLevels returns the level of the DefaultLogger followed by the levels of the registered loggers.
+func allLevels() []levelResponse {
+	levels := []levelResponse{{Name: DefaultLoggerName, Level: DefaultLogger.loadLogLevel().String()}}
+	for _, name := range RegisteredLoggerNames() {
+		if level, ok := levelOf(name); ok {
+			levels = append(levels, levelResponse{Name: name, Level: level.String()})
+		}
+	}
+	return levels
+}
+
+func levelOf(name string) (Level, bool) {
+	if name == DefaultLoggerName {
+		return DefaultLogger.loadLogLevel(), true
+	}
+	logger := GetLogger(name)
+	if logger == nil {
+		return LOG_PLAIN, false
+	}
+	return logger.loadLogLevel(), true
+}
+
+func readLevel(r *http.Request) (Level, error) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1024))
+	if err != nil {
+		return LOG_PLAIN, err
+	}
+
+	text := strings.TrimSpace(string(body))
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "application/json" {
+		request := levelRequest{}
+		if err := json.Unmarshal(body, &request); err != nil {
+			return LOG_PLAIN, err
+		}
+		text = request.Level
+	}
+	return ParseLevel(text)
+}
+
+// writeLevels writes the levels as plain text or, when JSON is requested, as array or as single object.
+func writeLevels(w http.ResponseWriter, r *http.Request, levels []levelResponse, single bool) {
+	if !strings.Contains(r.Header.Get("Accept"), "application/json") {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		if r.Method == http.MethodHead {
+			return
+		}
+		for _, level := range levels {
+			fmt.Fprintf(w, "%s %s\n", level.Name, level.Level)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method == http.MethodHead {
+		return
+	}
+	encoder := json.NewEncoder(w)
+	if single {
+		encoder.Encode(levels[0])
+		return
+	}
+	encoder.Encode(levels)
+}
@@ -0,0 +1,135 @@
+package sigolo
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseLevel(t *testing.T) {
+	level, err := ParseLevel("debug")
+	assertTrue(t, err == nil && level == LOG_DEBUG)
+	level, err = ParseLevel("FATAL")
+	assertTrue(t, err == nil && level == LOG_FATAL)
+	_, err = ParseLevel("verbose")
+	assertTrue(t, err != nil)
+}
+
+func serveLevelRequest(method string, target string, body string, header ...string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, target, strings.NewReader(body))
+	for i := 0; i+1 < len(header); i += 2 {
+		request.Header.Set(header[i], header[i+1])
+	}
+	response := httptest.NewRecorder()
+	LevelHandler{}.ServeHTTP(response, request)
+	return response
+}
+
+func registerTestLogger(t *testing.T, name string, level Level) (*Logger, *bytes.Buffer) {
+	logger, buffer := newBufferLogger(level)
+	if err := RegisterLogger(name, logger); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		UnregisterLogger(name)
+	})
+	return logger, buffer
+}
+
+func TestLevelHandlerGet(t *testing.T) {
+	prepareBuffer(t, LOG_INFO)
+	SetDefaultLogLevel(LOG_WARN)
+	t.Cleanup(func() {
+		SetDefaultLogLevel(LOG_INFO)
+	})
+	registerTestLogger(t, "db", LOG_DEBUG)
+	registerTestLogger(t, "api", LOG_ERROR)
+
+	response := serveLevelRequest(http.MethodGet, "/", "")
+	assertTrue(t, response.Code == http.StatusOK)
+	assertTrue(t, response.Body.String() == "default WARN\napi ERROR\ndb DEBUG\n")
+
+	response = serveLevelRequest(http.MethodGet, "/", "", "Accept", "application/json")
+	assertTrue(t, response.Header().Get("Content-Type") == "application/json")
+	assertTrue(t, response.Body.String() == `[{"name":"default","level":"WARN"},{"name":"api","level":"ERROR"},{"name":"db","level":"DEBUG"}]`+"\n")
+
+	response = serveLevelRequest(http.MethodGet, "/?logger=db", "", "Accept", "application/json")
+	assertTrue(t, response.Body.String() == `{"name":"db","level":"DEBUG"}`+"\n")
+
+	response = serveLevelRequest(http.MethodGet, "/?logger=unknown", "")
+	assertTrue(t, response.Code == http.StatusNotFound)
+
+	response = serveLevelRequest(http.MethodPost, "/", "")
+	assertTrue(t, response.Code == http.StatusMethodNotAllowed)
+	assertTrue(t, response.Header().Get("Allow") == "GET, HEAD, PUT")
+}
+
+func TestLevelHandlerHead(t *testing.T) {
+	registerTestLogger(t, "db", LOG_DEBUG)
+
+	response := serveLevelRequest(http.MethodHead, "/?logger=db", "")
+	assertTrue(t, response.Code == http.StatusOK)
+	assertTrue(t, response.Header().Get("Content-Type") == "text/plain; charset=utf-8")
+	assertTrue(t, response.Body.Len() == 0)
+
+	response = serveLevelRequest(http.MethodHead, "/", "", "Accept", "application/json")
+	assertTrue(t, response.Header().Get("Content-Type") == "application/json")
+	assertTrue(t, response.Body.Len() == 0)
+}
+
+func TestRegisterLoggerRejectsDefaultName(t *testing.T) {
+	logger, _ := newBufferLogger(LOG_DEBUG)
+
+	assertTrue(t, RegisterLogger(DefaultLoggerName, logger) != nil)
+	assertTrue(t, RegisterLogger("", logger) != nil)
+	assertTrue(t, GetLogger(DefaultLoggerName) == nil)
+}
+
+func TestLevelHandlerPut(t *testing.T) {
+	buffer := prepareBuffer(t, LOG_INFO)
+	SetDefaultLogLevel(LOG_INFO)
+	logger, loggerBuffer := registerTestLogger(t, "db", LOG_WARN)
+
+	response := serveLevelRequest(http.MethodPut, "/?logger=db", "debug\n")
+	assertTrue(t, response.Code == http.StatusOK)
+	assertTrue(t, response.Body.String() == "db DEBUG\n")
+	assertTrue(t, logger.LogLevel == LOG_DEBUG)
+	assertTrue(t, strings.HasSuffix(loggerBuffer.String(), "| Log level of db logger changed from WARN to DEBUG\n"))
+
+	response = serveLevelRequest(http.MethodPut, "/", `{"level":"ERROR"}`, "Content-Type", "application/json; charset=utf-8")
+	t.Cleanup(func() {
+		SetDefaultLogLevel(LOG_INFO)
+	})
+	assertTrue(t, response.Code == http.StatusOK)
+	assertTrue(t, GetCurrentLogLevel() == LOG_ERROR)
+	assertTrue(t, DefaultLogger.LogLevel == LOG_ERROR)
+	// Logged before the change, as the new level hides INFO lines.
+	assertTrue(t, strings.HasSuffix(buffer.String(), "| Log level of default logger changed from INFO to ERROR\n"))
+
+	response = serveLevelRequest(http.MethodPut, "/?logger=db", "verbose")
+	assertTrue(t, response.Code == http.StatusBadRequest)
+	assertTrue(t, logger.LogLevel == LOG_DEBUG)
+
+	response = serveLevelRequest(http.MethodPut, "/?logger=unknown", "DEBUG")
+	assertTrue(t, response.Code == http.StatusNotFound)
+}
+
+func TestStepDefaultLogLevel(t *testing.T) {
+	prepareBuffer(t, LOG_INFO)
+	SetDefaultLogLevel(LOG_DEBUG)
+	t.Cleanup(func() {
+		SetDefaultLogLevel(LOG_INFO)
+	})
+
+	stepDefaultLogLevel(-1)
+	assertTrue(t, GetCurrentLogLevel() == LOG_TRACE)
+	stepDefaultLogLevel(-1)
+	assertTrue(t, GetCurrentLogLevel() == LOG_TRACE)
+
+	for i := 0; i < 10; i++ {
+		stepDefaultLogLevel(1)
+	}
+	assertTrue(t, GetCurrentLogLevel() == LOG_FATAL)
+}
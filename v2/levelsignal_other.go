@@ -0,0 +1,8 @@
+//go:build !unix
+
+package sigolo
+
+// HandleLevelSignals does nothing on this platform, as it has no SIGUSR1 and SIGUSR2 signals.
+func HandleLevelSignals() (stop func()) {
+	return func() {}
+}
@@ -0,0 +1,47 @@
+//go:build unix
+
+package sigolo
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// levelSignalHandled is called after a signal has been handled, if set. It's used by tests to wait for the change.
+var levelSignalHandled func()
+
+// HandleLevelSignals changes the level of the DefaultLogger when the process receives a signal: SIGUSR1 makes it one
+// step more verbose until TRACE is reached, SIGUSR2 one step less verbose until FATAL is reached. Each change is logged.
+// The returned function stops handling the signals.
+//
+// This does nothing on platforms without these signals.
+func HandleLevelSignals() (stop func()) {
+	signals := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(signals, syscall.SIGUSR1, syscall.SIGUSR2)
+
+	handled := levelSignalHandled
+	go func() {
+		for {
+			select {
+			case received := <-signals:
+				if received == syscall.SIGUSR1 {
+					stepDefaultLogLevel(-1)
+				} else {
+					stepDefaultLogLevel(1)
+				}
+				if handled != nil {
+					handled()
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
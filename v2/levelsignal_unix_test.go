@@ -0,0 +1,44 @@
+//go:build unix
+
+package sigolo
+
+import (
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestHandleLevelSignals(t *testing.T) {
+	buffer := prepareBuffer(t, LOG_INFO)
+	SetDefaultLogLevel(LOG_INFO)
+	t.Cleanup(func() {
+		SetDefaultLogLevel(LOG_INFO)
+	})
+
+	handled := make(chan struct{})
+	levelSignalHandled = func() {
+		handled <- struct{}{}
+	}
+	stop := HandleLevelSignals()
+	levelSignalHandled = nil
+	defer stop()
+
+	syscall.Kill(syscall.Getpid(), syscall.SIGUSR1)
+	waitForLevelSignal(t, handled)
+	assertTrue(t, GetCurrentLogLevel() == LOG_DEBUG)
+	syscall.Kill(syscall.Getpid(), syscall.SIGUSR2)
+	waitForLevelSignal(t, handled)
+	assertTrue(t, GetCurrentLogLevel() == LOG_INFO)
+
+	assertTrue(t, strings.Contains(buffer.String(), "| Log level of default logger changed from INFO to DEBUG\n"))
+	assertTrue(t, strings.HasSuffix(buffer.String(), "| Log level of default logger changed from DEBUG to INFO\n"))
+}
+
+func waitForLevelSignal(t *testing.T, handled chan struct{}) {
+	select {
+	case <-handled:
+	case <-time.After(time.Second):
+		t.Fatal("Signal wasn't handled")
+	}
+}
@@ -4,12 +4,15 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"path"
-	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 )
 
-type Level int
+type Level int32
 
 const (
 	LOG_PLAIN Level = iota
@@ -21,10 +24,66 @@ const (
 	LOG_FATAL
 )
 
+// String returns the name of the level, e.g. "INFO".
+func (l Level) String() string {
+	switch l {
+	case LOG_PLAIN:
+		return "PLAIN"
+	case LOG_TRACE:
+		return "TRACE"
+	case LOG_DEBUG:
+		return "DEBUG"
+	case LOG_INFO:
+		return "INFO"
+	case LOG_WARN:
+		return "WARN"
+	case LOG_ERROR:
+		return "ERROR"
+	case LOG_FATAL:
+		return "FATAL"
+	}
+	return "Level(" + strconv.Itoa(int(l)) + ")"
+}
+
+// ParseLevel returns the level of the given name, e.g. "debug" or "DEBUG". The name is case-insensitive.
+func ParseLevel(name string) (Level, error) {
+	for level := LOG_PLAIN; level <= LOG_FATAL; level++ {
+		if strings.EqualFold(name, level.String()) {
+			return level, nil
+		}
+	}
+	return LOG_PLAIN, fmt.Errorf("unknown log level %q", name)
+}
+
+// TraceIdEnvironmentVariable is the environment variable used to hand the trace ID of a parent process to a child
+// process. When set, the DefaultLogger of the child has this trace ID, as its entries belong to the work of the parent.
+// Loggers created by the child, e.g. for its own requests, still get new trace IDs.
+const TraceIdEnvironmentVariable = "SIGOLO_TRACE_ID"
+
 var (
-	nextTraceId = 0
-	logLevel    = LOG_INFO
-	dateFormat  = "2006-01-02 15:04:05.000"
+	// traceIdMutex protects nextTraceId and traceIdGenerator, as new trace IDs are created concurrently.
+	traceIdMutex     sync.Mutex
+	nextTraceId      TraceId
+	traceIdGenerator TraceIdGenerator = NewSequentialTraceIdGenerator(1)
+	// inheritedTraceId is the trace ID handed over by a parent process, see TraceIdEnvironmentVariable.
+	inheritedTraceId, traceIdInherited = initialTraceId()
+
+	logLevel     = LOG_INFO
+	dateFormat   = "2006-01-02 15:04:05.000"
+	callerFormat = CALLER_FILE
+	timeLocation *time.Location
+	clock        Clock
+	timestamps   *timestamper
+	deduplicator *messageDeduplicator
+	sampler      *Sampler
+	recorder     *FlightRecorder
+	escalation   *Escalation
+	chromeTrace  *ChromeTrace
+	runtimeTrace bool
+	redactor     *Redactor
+	metrics      *Metrics
+	sanitization = SANITIZE_NONE
+	spanIndent   string
 
 	// The current maximum length printed for caller information. This is updated each time something gets printed
 	CallerColumnWidth = 0
@@ -32,12 +91,49 @@ var (
 	formatFunctions = DefaultStaticLogFormatFunctions()
 	levelStrings    = DefaultLevelStrings()
 	levelOutputs    = DefaultLevelOutputs()
+	hooks           = map[Level][]Hook{}
 
 	DefaultLogger = GetLoggerWithCurrentDefaults()
 )
 
-func DefaultLogFormatFunctions() map[Level]func(io.Writer, string, string, int, string, int, string) {
-	return map[Level]func(io.Writer, string, string, int, string, int, string){
+// initialTraceId returns the trace ID handed over by a parent process and true, or the trace ID 0 and false when there
+// is none.
+func initialTraceId() (TraceId, bool) {
+	traceId, err := ParseTraceId(os.Getenv(TraceIdEnvironmentVariable))
+	if err != nil {
+		return TraceId{}, false
+	}
+	return traceId, true
+}
+
+// FormatFunction writes one entry, e.g. LogDefault or LogJson. The entry is passed by value, so that writing it doesn't
+// allocate.
+type FormatFunction func(writer io.Writer, entry FormattedEntry)
+
+// FormattedEntry is an entry prepared for a FormatFunction. Further information about entries will be added as new
+// fields, so that existing format functions keep working.
+type FormattedEntry struct {
+	// Time is the formatted time of the entry.
+	Time  string
+	Level Level
+	// LevelString is the level as it's printed, e.g. "[INFO] ".
+	LevelString string
+	// CallerWidth is the length the caller is padded to, so that the messages of all entries are aligned.
+	CallerWidth int
+	Caller      string
+	TraceId     TraceId
+	// SpanId is 0 when the entry doesn't belong to a span.
+	SpanId SpanId
+	// Message is the message followed by the fields of the entry, sanitized according to the Sanitization of the logger.
+	Message string
+	// RawMessage is the message without sanitization for formats escaping it themselves, like LogJson.
+	RawMessage string
+	// Indent shows the nesting of spans. Text formats put it in front of every line of the message.
+	Indent string
+}
+
+func DefaultLogFormatFunctions() map[Level]FormatFunction {
+	return map[Level]FormatFunction{
 		LOG_PLAIN: LogPlain,
 		LOG_TRACE: LogDefault,
 		LOG_DEBUG: LogDefault,
@@ -48,8 +144,8 @@ func DefaultLogFormatFunctions() map[Level]func(io.Writer, string, string, int,
 	}
 }
 
-func DefaultStaticLogFormatFunctions() map[Level]func(io.Writer, string, string, int, string, int, string) {
-	return map[Level]func(io.Writer, string, string, int, string, int, string){
+func DefaultStaticLogFormatFunctions() map[Level]FormatFunction {
+	return map[Level]FormatFunction{
 		LOG_PLAIN: LogPlain,
 		LOG_TRACE: LogDefaultStatic,
 		LOG_DEBUG: LogDefaultStatic,
@@ -85,25 +181,54 @@ func DefaultLevelOutputs() map[Level]io.Writer {
 }
 
 func GetCurrentLogLevel() Level {
-	return logLevel
+	return Level(atomic.LoadInt32((*int32)(&logLevel)))
 }
 
 func GetCurrentDateFormat() string {
 	return dateFormat
 }
 
-func GetCurrentNextTraceId() int {
+func GetCurrentCallerFormat() CallerFormat {
+	return callerFormat
+}
+
+func GetCurrentNextTraceId() TraceId {
+	traceIdMutex.Lock()
+	defer traceIdMutex.Unlock()
 	return nextTraceId
 }
 
+// defaultTraceId returns the trace ID of the DefaultLogger, which continues the trace of a parent process.
+func defaultTraceId() TraceId {
+	if traceIdInherited {
+		return inheritedTraceId
+	}
+	return GetCurrentNextTraceId()
+}
+
 func GetLoggerWithCurrentDefaults() *Logger {
 	return &Logger{
-		LogTraceId:      nextTraceId,
-		LogLevel:        logLevel,
+		LogTraceId:      defaultTraceId(),
+		LogLevel:        GetCurrentLogLevel(),
 		DateFormat:      dateFormat,
+		TimeLocation:    timeLocation,
+		Clock:           clock,
+		CallerFormat:    callerFormat,
 		FormatFunctions: formatFunctions,
 		LevelStrings:    levelStrings,
 		LevelOutputs:    levelOutputs,
+		Hooks:           hooks,
+		Sampler:         sampler,
+		Recorder:        recorder,
+		Escalation:      escalation,
+		ChromeTrace:     chromeTrace,
+		RuntimeTrace:    runtimeTrace,
+		Sanitization:    sanitization,
+		Redactor:        redactor,
+		Metrics:         metrics,
+		deduplicator:    deduplicator,
+		timestamper:     timestamps,
+		spanIndent:      spanIndent,
 	}
 }
 
@@ -112,13 +237,87 @@ func SetDefaultDateFormat(format string) {
 	DefaultLogger = GetLoggerWithCurrentDefaults()
 }
 
-func SetDefaultLogLevel(level Level) {
-	logLevel = level
+// SetDefaultTimeLocation sets the location used to format timestamps of the default logger, e.g. time.UTC. Use nil
+// for the local time.
+func SetDefaultTimeLocation(location *time.Location) {
+	timeLocation = location
+	DefaultLogger = GetLoggerWithCurrentDefaults()
+}
+
+// SetDefaultClock sets the clock providing the time of entries of the default logger, e.g. a FakeClock in tests. Use
+// nil for the system time.
+func SetDefaultClock(c Clock) {
+	clock = c
+	DefaultLogger = GetLoggerWithCurrentDefaults()
+}
+
+// SetDefaultTimestampMode determines what the timestamps of the default logger show, see Logger.SetTimestampMode for
+// details.
+func SetDefaultTimestampMode(mode TimestampMode) {
+	timestamps = newTimestamper(mode, DefaultLogger.now())
+	DefaultLogger = GetLoggerWithCurrentDefaults()
+}
+
+// SetDefaultTraceIdGenerator sets the generator of trace IDs for new loggers and the package level functions. A trace ID
+// handed over by a parent process (see TraceIdEnvironmentVariable) is still used by the package level functions.
+func SetDefaultTraceIdGenerator(generator TraceIdGenerator) {
+	traceIdMutex.Lock()
+	traceIdGenerator = generator
+	nextTraceId = generator.NextTraceId()
+	traceIdMutex.Unlock()
+	DefaultLogger = GetLoggerWithCurrentDefaults()
+}
+
+func SetDefaultCallerFormat(format CallerFormat) {
+	callerFormat = format
+	DefaultLogger = GetLoggerWithCurrentDefaults()
+}
+
+// SetDefaultDeduplicationWindow enables collapsing of repeated messages for the default logger, see
+// Logger.SetDeduplicationWindow for details. A window of 0 disables it.
+func SetDefaultDeduplicationWindow(window time.Duration) {
+	if deduplicator != nil {
+		deduplicator.flush()
+	}
+	deduplicator = newMessageDeduplicator(window)
+	DefaultLogger = GetLoggerWithCurrentDefaults()
+}
+
+// SetDefaultSampler sets the sampler of the default logger, see NewSampler for details. Use nil to disable sampling.
+// The package-level functions use a new trace ID for each call, so their entries are sampled line by line.
+func SetDefaultSampler(s *Sampler) {
+	sampler = s
+	DefaultLogger = GetLoggerWithCurrentDefaults()
+}
+
+// SetDefaultFlightRecorder sets the flight recorder of the default logger, see NewFlightRecorder for details. Use nil
+// to disable it.
+func SetDefaultFlightRecorder(r *FlightRecorder) {
+	recorder = r
+	DefaultLogger = GetLoggerWithCurrentDefaults()
+}
+
+// SetDefaultRedactor sets the redactor of the default logger, see NewRedactor for details. Use nil to disable it.
+func SetDefaultRedactor(r *Redactor) {
+	redactor = r
+	DefaultLogger = GetLoggerWithCurrentDefaults()
+}
+
+// SetDefaultSanitization sets how line breaks and other control characters are printed by the default logger.
+func SetDefaultSanitization(mode Sanitization) {
+	sanitization = mode
 	DefaultLogger = GetLoggerWithCurrentDefaults()
 }
 
+// SetDefaultLogLevel changes the level of the default logger without replacing it, so other settings made on the
+// DefaultLogger are kept. It's safe to call this while other goroutines are logging.
+func SetDefaultLogLevel(level Level) {
+	atomic.StoreInt32((*int32)(&logLevel), int32(level))
+	DefaultLogger.SetLogLevel(level)
+}
+
 func ShouldLog(level Level) bool {
-	return logLevel <= level
+	return GetCurrentLogLevel() <= level || escalation != nil && escalation.enables(DefaultLogger, level)
 }
 
 func ShouldLogTrace() bool {
@@ -129,12 +328,12 @@ func ShouldLogDebug() bool {
 	return ShouldLog(LOG_DEBUG)
 }
 
-func SetDefaultFormatFunction(level Level, function func(io.Writer, string, string, int, string, int, string)) {
+func SetDefaultFormatFunction(level Level, function FormatFunction) {
 	formatFunctions[level] = function
 	DefaultLogger = GetLoggerWithCurrentDefaults()
 }
 
-func SetDefaultFormatFunctionAll(function func(io.Writer, string, string, int, string, int, string)) {
+func SetDefaultFormatFunctionAll(function FormatFunction) {
 	formatFunctions[LOG_PLAIN] = function
 	formatFunctions[LOG_TRACE] = function
 	formatFunctions[LOG_DEBUG] = function
@@ -156,7 +355,9 @@ func SetDefaultLevelOutput(level Level, prefix string) {
 }
 
 func Plain(message string) {
-	DefaultLogger.Plainb(1, "%s", message)
+	if DefaultLogger.shouldHandle(LOG_PLAIN) {
+		DefaultLogger.logMessage(LOG_PLAIN, 1, message)
+	}
 	increaseTraceId()
 }
 
@@ -172,7 +373,9 @@ func Plainb(framesBackward int, format string, args ...interface{}) {
 }
 
 func Trace(message string) {
-	DefaultLogger.Traceb(1, "%s", message)
+	if DefaultLogger.shouldHandle(LOG_TRACE) {
+		DefaultLogger.logMessage(LOG_TRACE, 1, message)
+	}
 	increaseTraceId()
 }
 
@@ -188,7 +391,9 @@ func Traceb(framesBackward int, format string, args ...interface{}) {
 }
 
 func Debug(message string) {
-	DefaultLogger.Debugb(1, "%s", message)
+	if DefaultLogger.shouldHandle(LOG_DEBUG) {
+		DefaultLogger.logMessage(LOG_DEBUG, 1, message)
+	}
 	increaseTraceId()
 }
 
@@ -204,7 +409,9 @@ func Debugb(framesBackward int, format string, args ...interface{}) {
 }
 
 func Info(message string) {
-	DefaultLogger.Infob(1, "%s", message)
+	if DefaultLogger.shouldHandle(LOG_INFO) {
+		DefaultLogger.logMessage(LOG_INFO, 1, message)
+	}
 	increaseTraceId()
 }
 
@@ -220,7 +427,9 @@ func Infob(framesBackward int, format string, args ...interface{}) {
 }
 
 func Warn(message string) {
-	DefaultLogger.Warnb(1, "%s", message)
+	if DefaultLogger.shouldHandle(LOG_WARN) {
+		DefaultLogger.logMessage(LOG_WARN, 1, message)
+	}
 	increaseTraceId()
 }
 
@@ -236,7 +445,9 @@ func Warnb(framesBackward int, format string, args ...interface{}) {
 }
 
 func Error(message string) {
-	DefaultLogger.Errorb(1, "%s", message)
+	if DefaultLogger.shouldHandle(LOG_ERROR) {
+		DefaultLogger.logMessage(LOG_ERROR, 1, message)
+	}
 	increaseTraceId()
 }
 
@@ -252,22 +463,24 @@ func Errorb(framesBackward int, format string, args ...interface{}) {
 }
 
 func Fatal(message string) {
-	DefaultLogger.Fatalb(1, "%s", message)
+	if DefaultLogger.shouldHandle(LOG_FATAL) {
+		DefaultLogger.logMessage(LOG_FATAL, 1, message)
+	}
 	increaseTraceId()
-	os.Exit(1)
+	exit()
 }
 
 func Fatalf(format string, args ...interface{}) {
 	DefaultLogger.Fatalb(1, format, args...)
 	increaseTraceId()
-	os.Exit(1)
+	exit()
 }
 
 // Fatalb is equal to Fatalf(...) but can go back in the stack and can therefore show function positions from previous functions.
 func Fatalb(framesBackward int, format string, args ...interface{}) {
 	DefaultLogger.Fatalb(1+framesBackward, format, args...)
 	increaseTraceId()
-	os.Exit(1)
+	exit()
 }
 
 // Stack tries to print the stack trace of the given error using the  %+v  format string. When using the
@@ -284,9 +497,14 @@ func Stackb(framesBackward int, err error) {
 	increaseTraceId()
 }
 
+// DumpRecent writes the recent entries of the default flight recorder, which haven't been written yet.
+func DumpRecent() {
+	DefaultLogger.DumpRecent()
+}
+
 // FatalCheckf checks if the error exists (!= nil). If so, it'll print the error
 // message and fatals with the given format message.
-func FatalCheckf(err error, traceId int, format string, args ...interface{}) {
+func FatalCheckf(err error, traceId TraceId, format string, args ...interface{}) {
 	if err != nil {
 		Stackb(1, err)
 		if args != nil {
@@ -301,63 +519,169 @@ func FatalCheckf(err error, traceId int, format string, args ...interface{}) {
 func FatalCheck(err error) {
 	if err != nil {
 		Stackb(1, err)
-		os.Exit(1)
+		exit()
 	}
 }
 
-func internalFatalf(traceId int, format string, args ...interface{}) {
+func internalFatalf(traceId TraceId, format string, args ...interface{}) {
 	internalLog(LOG_FATAL, traceId, fmt.Sprintf(format, args...))
-	os.Exit(1)
+	exit()
 }
 
-func internalLog(level Level, traceId int, message string) {
+func internalLog(level Level, traceId TraceId, message string) {
 	// A bit hacky: We know here that the stack contains three calls from inside
 	// this file. The third frame comes from the file that initially called a
 	// function in this file (e.g. Infof())
-	caller := GetCallerDetails(4)
+	caller := getCallerDetails(4, DefaultLogger.CallerFormat)
 
-	updateCallerColumnWidth(caller)
+	DefaultLogger.logCaller(level, caller, traceId, message)
+}
 
-	formatFunctions[level](levelOutputs[level], time.Now().Format(dateFormat), levelStrings[level], CallerColumnWidth, caller, traceId, message)
+// exit terminates the application after pending entries (e.g. repeated messages) have been written.
+func exit() {
+	flushAllDeduplicators()
+	os.Exit(1)
 }
 
-func updateCallerColumnWidth(caller string) {
-	if len(caller) > CallerColumnWidth {
-		CallerColumnWidth = len(caller)
+// formatMessage formats the message like fmt.Sprintf does. Arguments of type func() string are lazy arguments: They are
+// called and replaced by their result, so that expensive values are only computed when the entry is actually printed.
+func formatMessage(format string, args []interface{}) string {
+	for i, arg := range args {
+		if _, isLazy := arg.(func() string); isLazy {
+			args = resolveLazyArguments(args, i)
+			break
+		}
 	}
+	return fmt.Sprintf(format, args...)
 }
 
-func GetCallerDetails(framesBackwards int) string {
-	name := "???"
-	line := -1
-	ok := false
+// resolveLazyArguments returns a copy of the arguments where each lazy argument, starting at the given index, is
+// replaced by its value. The arguments of the caller are not changed.
+func resolveLazyArguments(args []interface{}, firstLazyIndex int) []interface{} {
+	resolvedArgs := make([]interface{}, len(args))
+	copy(resolvedArgs, args)
+
+	for i := firstLazyIndex; i < len(resolvedArgs); i++ {
+		if lazyArg, isLazy := resolvedArgs[i].(func() string); isLazy {
+			resolvedArgs[i] = lazyArg()
+		}
+	}
 
-	_, name, line, ok = runtime.Caller(framesBackwards)
+	return resolvedArgs
+}
 
-	if ok {
-		name = path.Base(name)
+func updateCallerColumnWidth(caller string) {
+	if len(caller) > CallerColumnWidth {
+		CallerColumnWidth = len(caller)
 	}
+}
 
-	caller := fmt.Sprintf("%s:%d", name, line)
+// GetCallerDetails returns the caller of the given frame using the default caller format. Frame 0 is GetCallerDetails
+// itself.
+func GetCallerDetails(framesBackwards int) string {
+	return getCallerDetails(framesBackwards+1, callerFormat)
+}
 
-	return caller
+// formatCaller returns "file:line" without going through the fmt package.
+func formatCaller(file string, line int) string {
+	var data [128]byte
+	caller := append(data[:0], file...)
+	caller = append(caller, ':')
+	caller = strconv.AppendInt(caller, int64(line), 10)
+	return string(caller)
 }
 
 // increaseTraceId increases the trace ID of the default logger. This has the effect, that the caller doesn't know that
-// in the background the same DefaultLogger instance is "recycled".
+// in the background the same DefaultLogger instance is "recycled". A trace ID inherited from the parent process is kept.
 func increaseTraceId() {
-	DefaultLogger.LogTraceId++
-	nextTraceId++
+	if traceIdInherited {
+		return
+	}
+	_, next := advanceTraceId()
+	DefaultLogger.LogTraceId = next
 }
 
-func LogDefault(writer io.Writer, time string, level string, maxLength int, caller string, traceId int, message string) {
-	fmt.Fprintf(writer, "%s %s %-*s | #%x | %s\n", time, level, maxLength, caller, traceId, message)
+// newTraceId creates a new trace ID, which differs from the one of the DefaultLogger. It's safe for concurrent use.
+func newTraceId() TraceId {
+	_, traceId := advanceTraceId()
+	return traceId
 }
 
-func LogDefaultStatic(writer io.Writer, time string, level string, maxLength int, caller string, traceId int, message string) {
-	fmt.Fprintf(writer, "%s %s %-*s | %s\n", time, level, maxLength, caller, message)
+// advanceTraceId returns the next trace ID and the newly created one after it.
+func advanceTraceId() (TraceId, TraceId) {
+	traceIdMutex.Lock()
+	defer traceIdMutex.Unlock()
+
+	traceId := nextTraceId
+	nextTraceId = traceIdGenerator.NextTraceId()
+	return traceId, nextTraceId
+}
+
+// LogDefault prints the time, level, caller, trace ID and message of an entry. The span ID, if there is one, is
+// appended to the trace ID like "#4bf92f3577b34da6a3ce929d0e0e4736/00f067aa0ba902b7".
+func LogDefault(writer io.Writer, entry FormattedEntry) {
+	buffer := getBuffer()
+	*buffer = appendDefaultPrefix(*buffer, entry)
+	*buffer = append(*buffer, " | #"...)
+	*buffer = entry.TraceId.Append(*buffer)
+	if entry.SpanId != 0 {
+		*buffer = append(*buffer, '/')
+		*buffer = entry.SpanId.Append(*buffer)
+	}
+	*buffer = append(*buffer, " | "...)
+	*buffer = appendIndented(*buffer, entry.Indent, entry.Message)
+	*buffer = append(*buffer, '\n')
+	writer.Write(*buffer)
+	putBuffer(buffer)
+}
+
+// LogDefaultStatic is equal to LogDefault but without the trace ID.
+func LogDefaultStatic(writer io.Writer, entry FormattedEntry) {
+	buffer := getBuffer()
+	*buffer = appendDefaultPrefix(*buffer, entry)
+	*buffer = append(*buffer, " | "...)
+	*buffer = appendIndented(*buffer, entry.Indent, entry.Message)
+	*buffer = append(*buffer, '\n')
+	writer.Write(*buffer)
+	putBuffer(buffer)
+}
+
+// LogPlain only prints the message.
+func LogPlain(writer io.Writer, entry FormattedEntry) {
+	buffer := getBuffer()
+	*buffer = append(*buffer, entry.Message...)
+	*buffer = append(*buffer, '\n')
+	writer.Write(*buffer)
+	putBuffer(buffer)
+}
+
+// appendDefaultPrefix appends "<time> <level> <caller>" with the caller padded to the caller width.
+func appendDefaultPrefix(buffer []byte, entry FormattedEntry) []byte {
+	buffer = append(buffer, entry.Time...)
+	buffer = append(buffer, ' ')
+	buffer = append(buffer, entry.LevelString...)
+	buffer = append(buffer, ' ')
+	buffer = append(buffer, entry.Caller...)
+	for padding := entry.CallerWidth - utf8.RuneCountInString(entry.Caller); padding > 0; padding-- {
+		buffer = append(buffer, ' ')
+	}
+	return buffer
 }
 
-func LogPlain(writer io.Writer, time string, level string, maxLength int, caller string, traceId int, message string) {
-	fmt.Fprintf(writer, "%s\n", message)
+// appendIndented appends the message with the indentation in front of every line.
+func appendIndented(buffer []byte, indent string, message string) []byte {
+	if indent == "" {
+		return append(buffer, message...)
+	}
+
+	buffer = append(buffer, indent...)
+	for {
+		lineEnd := strings.IndexByte(message, '\n')
+		if lineEnd == -1 {
+			return append(buffer, message...)
+		}
+		buffer = append(buffer, message[:lineEnd+1]...)
+		buffer = append(buffer, indent...)
+		message = message[lineEnd+1:]
+	}
 }
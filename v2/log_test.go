@@ -1,6 +1,7 @@
 package sigolo
 
 import (
+	"bytes"
 	"fmt"
 	"log"
 	"os"
@@ -19,6 +20,19 @@ func prepare(logLevel Level) *os.File {
 	return readPipe
 }
 
+// prepareBuffer is like prepare(...) but uses a buffer as output, which is reset to the default output after the test.
+func prepareBuffer(t *testing.T, logLevel Level) *bytes.Buffer {
+	SetDefaultLogLevel(LOG_PLAIN)
+
+	buffer := &bytes.Buffer{}
+	levelOutputs[logLevel] = buffer
+	t.Cleanup(func() {
+		levelOutputs[logLevel] = DefaultLevelOutputs()[logLevel]
+	})
+
+	return buffer
+}
+
 func cutOutput(f *os.File) (string, string) {
 	data := make([]byte, 2<<10)
 	f.Read(data)
@@ -316,6 +330,22 @@ func TestShouldLog(t *testing.T) {
 	assertTrue(t, ShouldLogTrace())
 }
 
+func TestSetDefaultLogLevelKeepsDefaultLogger(t *testing.T) {
+	logger := DefaultLogger
+	logger.LogTraceId = SequentialTraceId(0x2a)
+	t.Cleanup(func() {
+		SetDefaultLogLevel(LOG_INFO)
+		DefaultLogger = GetLoggerWithCurrentDefaults()
+	})
+
+	SetDefaultLogLevel(LOG_DEBUG)
+
+	assertTrue(t, DefaultLogger == logger)
+	assertTrue(t, DefaultLogger.LogTraceId == SequentialTraceId(0x2a))
+	assertTrue(t, DefaultLogger.LogLevel == LOG_DEBUG)
+	assertTrue(t, GetCurrentLogLevel() == LOG_DEBUG)
+}
+
 // TODO more test regarding the caller information (function name and line)
 
 func assertTrue(t *testing.T, b bool) {
@@ -3,25 +3,86 @@ package sigolo
 import (
 	"fmt"
 	"io"
+	"strings"
+	"sync/atomic"
 	"time"
 )
 
 type Logger struct {
-	LogTraceId      int
+	LogTraceId      TraceId
 	LogLevel        Level
 	DateFormat      string
-	FormatFunctions map[Level]func(io.Writer, string, string, int, string, int, string)
+	CallerFormat    CallerFormat
+	FormatFunctions map[Level]FormatFunction
 	LevelStrings    map[Level]string
 	LevelOutputs    map[Level]io.Writer
+	// LogSpanId identifies the current operation within the trace, e.g. the handling of one request. 0 means there's no
+	// span, see NewLoggerFromRequest(...).
+	LogSpanId SpanId
+	// LogParentSpanId is the span that caused the current one, e.g. the span of the client sending a request.
+	LogParentSpanId SpanId
+	// TraceFlags and TraceState are passed on to outgoing requests, see InjectTraceContext(...).
+	TraceFlags TraceFlags
+	TraceState TraceState
+	// TimeLocation is the location used to format timestamps, e.g. time.UTC. When nil, the location of the times
+	// returned by the clock is used, which is the local time for the SystemClock.
+	TimeLocation *time.Location
+	// Clock provides the time of entries. When nil, time.Now() is used.
+	Clock Clock
+	// Hooks are called for each entry of their level before it's formatted, see AddHook(...).
+	Hooks map[Level][]Hook
+	// Observers are called for each written entry, see AddObserver(...).
+	Observers []Observer
+	// Sampler drops a part of the entries when set, see NewSampler(...).
+	Sampler *Sampler
+	// Sanitization determines how line breaks and other control characters in messages and fields are printed.
+	Sanitization Sanitization
+	// Redactor removes secrets from messages and fields when set, see NewRedactor(...).
+	Redactor *Redactor
+	// Escalation temporarily lowers the log level after errors when set, see NewEscalation(...).
+	Escalation *Escalation
+	// Recorder keeps the recent entries of all levels when set, see NewFlightRecorder(...).
+	Recorder *FlightRecorder
+	// Metrics counts the written and dropped entries when set, see EnableMetrics(...).
+	Metrics *Metrics
+	// ChromeTrace records spans and entries for a timeline when set, see NewChromeTrace(...).
+	ChromeTrace *ChromeTrace
+	// RuntimeTrace emits each entry via runtime/trace.Log with the level as category, so that entries are visible in
+	// "go tool trace".
+	RuntimeTrace bool
+
+	// deduplicator collapses repeated messages, see SetDeduplicationWindow(...).
+	deduplicator *messageDeduplicator
+	// entryBuffer keeps all entries until Commit() or Discard() is called, see NewBufferedLogger(...).
+	entryBuffer *entryBuffer
+	// timestamper formats relative timestamps, see SetTimestampMode(...).
+	timestamper *timestamper
+	// spanDepth is the number of spans this logger is nested in and spanIndent is put in front of messages once per
+	// depth, see Span(...) and SetSpanIndent(...).
+	spanDepth  int
+	spanIndent string
+}
+
+// Entry is a single log entry before it gets formatted.
+type Entry struct {
+	Time    time.Time
+	Level   Level
+	Caller  string
+	TraceId TraceId
+	SpanId  SpanId
+	Message string
+	Fields  []Field
 }
 
 func NewLogger() *Logger {
-	traceId := nextTraceId
-	nextTraceId++
+	traceId := newTraceId()
 	return &Logger{
 		LogTraceId:      traceId,
-		LogLevel:        logLevel,
+		LogLevel:        GetCurrentLogLevel(),
 		DateFormat:      dateFormat,
+		TimeLocation:    timeLocation,
+		Clock:           clock,
+		CallerFormat:    callerFormat,
 		FormatFunctions: DefaultLogFormatFunctions(),
 		LevelStrings:    DefaultLevelStrings(),
 		LevelOutputs:    DefaultLevelOutputs(),
@@ -29,23 +90,24 @@ func NewLogger() *Logger {
 }
 
 func NewLoggerl(logLevel Level) *Logger {
-	traceId := nextTraceId
-	nextTraceId++
+	traceId := newTraceId()
 	return &Logger{
 		LogTraceId:      traceId,
 		LogLevel:        logLevel,
 		DateFormat:      dateFormat,
+		TimeLocation:    timeLocation,
+		Clock:           clock,
+		CallerFormat:    callerFormat,
 		FormatFunctions: DefaultLogFormatFunctions(),
 		LevelStrings:    DefaultLevelStrings(),
 		LevelOutputs:    DefaultLevelOutputs(),
 	}
 }
 
-func NewLoggerf(logLevel Level, defaultFormat func(io.Writer, string, string, int, string, int, string)) *Logger {
-	traceId := nextTraceId
-	nextTraceId++
+func NewLoggerf(logLevel Level, defaultFormat FormatFunction) *Logger {
+	traceId := newTraceId()
 
-	formatFunctions := map[Level]func(io.Writer, string, string, int, string, int, string){
+	formatFunctions := map[Level]FormatFunction{
 		LOG_PLAIN: defaultFormat,
 		LOG_TRACE: defaultFormat,
 		LOG_DEBUG: defaultFormat,
@@ -58,6 +120,9 @@ func NewLoggerf(logLevel Level, defaultFormat func(io.Writer, string, string, in
 		LogTraceId:      traceId,
 		LogLevel:        logLevel,
 		DateFormat:      dateFormat,
+		TimeLocation:    timeLocation,
+		Clock:           clock,
+		CallerFormat:    callerFormat,
 		FormatFunctions: formatFunctions,
 		LevelStrings:    DefaultLevelStrings(),
 		LevelOutputs:    DefaultLevelOutputs(),
@@ -65,139 +130,383 @@ func NewLoggerf(logLevel Level, defaultFormat func(io.Writer, string, string, in
 }
 
 func (l *Logger) Plain(message string) {
-	l.Plainb(1, "%s", message)
+	if !l.shouldHandle(LOG_PLAIN) {
+		return
+	}
+	l.logMessage(LOG_PLAIN, 1, message)
 }
 
 func (l *Logger) Plainf(format string, args ...interface{}) {
-	l.Plainb(1, fmt.Sprintf(format, args...))
+	if !l.shouldHandle(LOG_PLAIN) {
+		return
+	}
+	l.logFormat(LOG_PLAIN, 1, format, args)
 }
 
 // Plainb is equal to Plainf(...) but can go back in the stack and can therefore show function positions from previous functions.
 func (l *Logger) Plainb(framesBackward int, format string, args ...interface{}) {
-	if l.LogLevel > LOG_PLAIN {
+	if !l.shouldHandle(LOG_PLAIN) {
 		return
 	}
-	l.log(LOG_PLAIN, 3+framesBackward, l.LogTraceId, fmt.Sprintf(format, args...))
+	l.logFormat(LOG_PLAIN, 1+framesBackward, format, args)
 }
 
 func (l *Logger) Trace(message string) {
-	l.Traceb(1, "%s", message)
+	if !l.shouldHandle(LOG_TRACE) {
+		return
+	}
+	l.logMessage(LOG_TRACE, 1, message)
 }
 
 func (l *Logger) Tracef(format string, args ...interface{}) {
-	l.Traceb(1, fmt.Sprintf(format, args...))
+	if !l.shouldHandle(LOG_TRACE) {
+		return
+	}
+	l.logFormat(LOG_TRACE, 1, format, args)
 }
 
 // Traceb is equal to Tracef(...) but can go back in the stack and can therefore show function positions from previous functions.
 func (l *Logger) Traceb(framesBackward int, format string, args ...interface{}) {
-	if l.LogLevel > LOG_TRACE {
+	if !l.shouldHandle(LOG_TRACE) {
 		return
 	}
-	l.log(LOG_TRACE, 3+framesBackward, l.LogTraceId, fmt.Sprintf(format, args...))
+	l.logFormat(LOG_TRACE, 1+framesBackward, format, args)
 }
 
 func (l *Logger) Debug(message string) {
-	l.Debugb(1, "%s", message)
+	if !l.shouldHandle(LOG_DEBUG) {
+		return
+	}
+	l.logMessage(LOG_DEBUG, 1, message)
 }
 
 func (l *Logger) Debugf(format string, args ...interface{}) {
-	l.Debugb(1, fmt.Sprintf(format, args...))
+	if !l.shouldHandle(LOG_DEBUG) {
+		return
+	}
+	l.logFormat(LOG_DEBUG, 1, format, args)
 }
 
+// Debugb is equal to Debugf(...) but can go back in the stack and can therefore show function positions from previous functions.
 func (l *Logger) Debugb(framesBackward int, format string, args ...interface{}) {
-	if logLevel > LOG_DEBUG {
+	if !l.shouldHandle(LOG_DEBUG) {
 		return
 	}
-	l.log(LOG_DEBUG, 3+framesBackward, l.LogTraceId, fmt.Sprintf(format, args...))
+	l.logFormat(LOG_DEBUG, 1+framesBackward, format, args)
 }
 
 func (l *Logger) Info(message string) {
-	l.Infob(1, "%s", message)
+	if !l.shouldHandle(LOG_INFO) {
+		return
+	}
+	l.logMessage(LOG_INFO, 1, message)
 }
 
 func (l *Logger) Infof(format string, args ...interface{}) {
-	l.Infob(1, fmt.Sprintf(format, args...))
+	if !l.shouldHandle(LOG_INFO) {
+		return
+	}
+	l.logFormat(LOG_INFO, 1, format, args)
 }
 
+// Infob is equal to Infof(...) but can go back in the stack and can therefore show function positions from previous functions.
 func (l *Logger) Infob(framesBackward int, format string, args ...interface{}) {
-	if logLevel > LOG_INFO {
+	if !l.shouldHandle(LOG_INFO) {
 		return
 	}
-	l.log(LOG_INFO, 3+framesBackward, l.LogTraceId, fmt.Sprintf(format, args...))
+	l.logFormat(LOG_INFO, 1+framesBackward, format, args)
 }
 
 func (l *Logger) Warn(message string) {
-	l.Warnb(1, "%s", message)
+	if !l.shouldHandle(LOG_WARN) {
+		return
+	}
+	l.logMessage(LOG_WARN, 1, message)
 }
 
 func (l *Logger) Warnf(format string, args ...interface{}) {
-	l.Warnb(1, fmt.Sprintf(format, args...))
+	if !l.shouldHandle(LOG_WARN) {
+		return
+	}
+	l.logFormat(LOG_WARN, 1, format, args)
 }
 
+// Warnb is equal to Warnf(...) but can go back in the stack and can therefore show function positions from previous functions.
 func (l *Logger) Warnb(framesBackward int, format string, args ...interface{}) {
-	if logLevel > LOG_WARN {
+	if !l.shouldHandle(LOG_WARN) {
 		return
 	}
-	l.log(LOG_WARN, 3+framesBackward, l.LogTraceId, fmt.Sprintf(format, args...))
+	l.logFormat(LOG_WARN, 1+framesBackward, format, args)
 }
 
 func (l *Logger) Error(message string) {
-	l.Errorb(1, "%s", message)
+	if !l.shouldHandle(LOG_ERROR) {
+		return
+	}
+	l.logMessage(LOG_ERROR, 1, message)
 }
 
 func (l *Logger) Errorf(format string, args ...interface{}) {
-	l.Errorb(1, fmt.Sprintf(format, args...))
+	if !l.shouldHandle(LOG_ERROR) {
+		return
+	}
+	l.logFormat(LOG_ERROR, 1, format, args)
 }
 
+// Errorb is equal to Errorf(...) but can go back in the stack and can therefore show function positions from previous functions.
 func (l *Logger) Errorb(framesBackward int, format string, args ...interface{}) {
-	if logLevel > LOG_ERROR {
+	if !l.shouldHandle(LOG_ERROR) {
 		return
 	}
-	l.log(LOG_ERROR, 3+framesBackward, l.LogTraceId, fmt.Sprintf(format, args...))
+	l.logFormat(LOG_ERROR, 1+framesBackward, format, args)
 }
 
 func (l *Logger) Fatal(message string) {
-	l.Fatalb(1, "%s", message)
+	if !l.shouldHandle(LOG_FATAL) {
+		return
+	}
+	l.logMessage(LOG_FATAL, 1, message)
 }
 
 func (l *Logger) Fatalf(format string, args ...interface{}) {
-	l.Fatalb(1, fmt.Sprintf(format, args...))
+	if !l.shouldHandle(LOG_FATAL) {
+		return
+	}
+	l.logFormat(LOG_FATAL, 1, format, args)
 }
 
+// Fatalb is equal to Fatalf(...) but can go back in the stack and can therefore show function positions from previous functions.
 func (l *Logger) Fatalb(framesBackward int, format string, args ...interface{}) {
-	if logLevel > LOG_FATAL {
+	if !l.shouldHandle(LOG_FATAL) {
 		return
 	}
-	l.log(LOG_FATAL, 3+framesBackward, l.LogTraceId, fmt.Sprintf(format, args...))
+	l.logFormat(LOG_FATAL, 1+framesBackward, format, args)
 }
 
 // Stack tries to print the stack trace of the given error using the  %+v  format string. When using the
 // https://github.com/pkg/errors package, this will print a full stack trace of the error. If normal errors are used,
 // this function will just print the error.
 func (l *Logger) Stack(err error) {
-	if logLevel > LOG_ERROR {
+	if !l.shouldHandle(LOG_ERROR) {
 		return
 	}
-	// Directly call "log" to avoid extra function call
-	l.log(LOG_ERROR, 3, l.LogTraceId, fmt.Sprintf("%+v", err))
+	l.logFormat(LOG_ERROR, 1, "%+v", []interface{}{err})
 }
 
 // Stackb is equal to Stack(...) but can go back in the stack and can therefore show function positions from previous functions.
 func (l *Logger) Stackb(framesBackward int, err error) {
-	if logLevel > LOG_ERROR {
+	if !l.shouldHandle(LOG_ERROR) {
+		return
+	}
+	l.logFormat(LOG_ERROR, 1+framesBackward, "%+v", []interface{}{err})
+}
+
+// SetLogLevel changes the LogLevel of this logger. It's safe to call this while other goroutines are logging with the
+// logger, e.g. from a signal handler or the LevelHandler. Copies of the logger, e.g. spans, keep their own level.
+func (l *Logger) SetLogLevel(level Level) {
+	atomic.StoreInt32((*int32)(&l.LogLevel), int32(level))
+}
+
+// loadLogLevel reads the LogLevel, which may be changed concurrently by SetLogLevel(...).
+func (l *Logger) loadLogLevel() Level {
+	return Level(atomic.LoadInt32((*int32)(&l.LogLevel)))
+}
+
+// ShouldLog returns true when entries of the given level are printed by this logger.
+func (l *Logger) ShouldLog(level Level) bool {
+	return l.loadLogLevel() <= level || l.Escalation != nil && l.Escalation.enables(l, level)
+}
+
+// shouldHandle returns true when entries of the given level are either printed or recorded by this logger. Callers of
+// logMessage and logFormat use this to return early without any costs.
+func (l *Logger) shouldHandle(level Level) bool {
+	return l.ShouldLog(level) || l.Recorder != nil || l.entryBuffer != nil
+}
+
+// shouldLogSampled returns true when the level is enabled and the sampler, if any, keeps the entry. Buffered loggers
+// accept all entries, the level is checked when the buffer is discarded.
+func (l *Logger) shouldLogSampled(level Level) bool {
+	if l.entryBuffer != nil {
+		return true
+	}
+	if !l.ShouldLog(level) {
+		return false
+	}
+	if l.Sampler != nil && !l.Sampler.Keep(level, l.LogTraceId) {
+		l.drop(level, DROP_SAMPLER)
+		return false
+	}
+	return true
+}
+
+// drop counts an entry of an enabled level, which isn't written for the given reason.
+func (l *Logger) drop(level Level, reason DropReason) {
+	if l.Metrics != nil {
+		l.Metrics.drop(level, reason)
+	}
+}
+
+// logMessage logs the message as it is. The caller must check shouldHandle(...) before, so that disabled levels cost
+// nothing.
+func (l *Logger) logMessage(level Level, framesBackward int, message string) {
+	l.logThrottled(nil, level, 1+framesBackward, message, nil, false)
+}
+
+// logFormat formats and logs the message. The caller must check shouldHandle(...) before, so that nothing gets formatted
+// for disabled levels.
+func (l *Logger) logFormat(level Level, framesBackward int, format string, args []interface{}) {
+	l.logThrottled(nil, level, 1+framesBackward, format, args, true)
+}
+
+// logThrottled records and logs the message unless the throttle, if any, suppresses its call site. The throttle is
+// checked before the message gets formatted. Throttled entries are recorded as not written, so that a dump contains them.
+func (l *Logger) logThrottled(t *throttle, level Level, framesBackward int, format string, args []interface{}, isFormat bool) {
+	written := l.shouldLogSampled(level)
+	if l.Recorder == nil && !written {
+		return
+	}
+	now := l.now()
+	programCounter := getCallerProgramCounter(2 + framesBackward)
+
+	suppressed := 0
+	if written && t != nil {
+		written, suppressed = t.allow(programCounter, now)
+		if !written {
+			l.drop(level, DROP_THROTTLE)
+		}
+	}
+	if l.Recorder != nil {
+		l.Recorder.record(now, level, programCounter, l.LogTraceId, l.LogSpanId, format, args, isFormat, written)
+	}
+	if !written {
+		return
+	}
+
+	message := format
+	if isFormat {
+		message = formatMessage(format, args)
+	}
+	l.log(now, level, programCounter, l.LogTraceId, suppressed, message)
+}
+
+// log writes the message of the given call site. The time is read once by the caller and used for the whole entry, so
+// that e.g. a stepping FakeClock advances exactly once per entry. When the throttle suppressed earlier calls of the call
+// site, a line with their number is written before.
+func (l *Logger) log(now time.Time, level Level, programCounter uintptr, traceId TraceId, suppressed int, message string) {
+	caller := formatCallerOf(programCounter, l.CallerFormat)
+
+	if suppressed > 0 {
+		l.logCallerAt(now, level, caller, traceId, fmt.Sprintf("Suppressed %d similar messages", suppressed))
+	}
+
+	if l.Recorder != nil && level >= l.Recorder.DumpLevel {
+		l.Recorder.dump(l)
+	}
+
+	l.logCallerAt(now, level, caller, traceId, message)
+}
+
+// logCaller is equal to log(...) but uses the given caller information instead of determining it from the stack.
+func (l *Logger) logCaller(level Level, caller string, traceId TraceId, message string) {
+	l.logCallerAt(l.now(), level, caller, traceId, message)
+}
+
+// logCallerAt is equal to logCaller(...) but uses the given time instead of reading the clock.
+func (l *Logger) logCallerAt(now time.Time, level Level, caller string, traceId TraceId, message string) {
+	if l.Escalation != nil && l.entryBuffer == nil {
+		// Announce the end of an expired escalation before this entry and the start of a new one after it.
+		l.Escalation.announceEnd(l, now, caller, traceId)
+		if level >= l.Escalation.TriggerLevel {
+			defer l.Escalation.start(l, now, level, caller, traceId)
+		}
+	}
+
+	entry := Entry{
+		Time:    now,
+		Level:   level,
+		Caller:  caller,
+		TraceId: traceId,
+		SpanId:  l.LogSpanId,
+		Message: message,
+	}
+
+	// Redact before the hooks, so that they never see secrets.
+	if l.Redactor != nil {
+		l.Redactor.redactEntry(&entry)
+	}
+
+	if len(l.Hooks[level]) > 0 {
+		// Hooks may keep the entry, so give them a copy to keep the entry on the stack when there are no hooks.
+		hookedEntry := entry
+		if !l.runHooks(&hookedEntry) {
+			l.drop(level, DROP_HOOK)
+			return
+		}
+		if l.Redactor != nil {
+			// Redact what the hooks added or changed as well.
+			if hookedEntry.Message != entry.Message {
+				hookedEntry.Message = l.Redactor.Redact(hookedEntry.Message)
+			}
+			l.Redactor.redactFields(&hookedEntry)
+		}
+		entry = hookedEntry
+	}
+
+	if l.entryBuffer != nil {
+		l.entryBuffer.add(&entry)
 		return
 	}
-	// Directly call "log" to avoid extra function call
-	l.log(LOG_ERROR, 3+framesBackward, l.LogTraceId, fmt.Sprintf("%+v", err))
+
+	if l.deduplicator != nil {
+		flushed, isNew := l.deduplicator.add(l, &entry)
+		flushed.write()
+		if !isNew {
+			l.drop(level, DROP_DEDUPLICATION)
+			return
+		}
+	}
+
+	l.write(&entry)
 }
 
-func (l *Logger) log(level Level, framesBackward int, traceId int, message string) {
-	// A bit hacky: We know here that the stack contains two calls from inside
-	// this file. The third frame comes from the file that initially called a
-	// function in this file (e.g. Infof())
-	caller := GetCallerDetails(framesBackward)
+// write formats the entry using the format function of its level.
+func (l *Logger) write(entry *Entry) {
+	updateCallerColumnWidth(entry.Caller)
 
-	updateCallerColumnWidth(caller)
+	rawMessage := entry.Message
+	message := sanitize(rawMessage, l.Sanitization)
+	if len(entry.Fields) > 0 {
+		rawMessage = messageWithFields(entry, SANITIZE_NONE)
+		message = messageWithFields(entry, l.Sanitization)
+	}
+	if l.ChromeTrace != nil {
+		l.ChromeTrace.recordEntry(entry)
+	}
+	if l.Metrics != nil {
+		l.Metrics.count(entry)
+	}
+	for _, observer := range l.Observers {
+		observer(entry)
+	}
+	if l.RuntimeTrace {
+		logRuntimeTrace(entry.Level, message)
+	}
+
+	indent := ""
+	if l.spanDepth > 0 && l.spanIndent != "" {
+		indent = strings.Repeat(l.spanIndent, l.spanDepth)
+	}
 
-	l.FormatFunctions[level](l.LevelOutputs[level], time.Now().Format(l.DateFormat), l.LevelStrings[level], CallerColumnWidth, caller, traceId, message)
+	l.FormatFunctions[entry.Level](l.LevelOutputs[entry.Level], FormattedEntry{
+		Time:        l.formatTime(entry.Time),
+		Level:       entry.Level,
+		LevelString: l.LevelStrings[entry.Level],
+		CallerWidth: CallerColumnWidth,
+		Caller:      entry.Caller,
+		TraceId:     entry.TraceId,
+		SpanId:      entry.SpanId,
+		Message:     message,
+		RawMessage:  rawMessage,
+		Indent:      indent,
+	})
 }
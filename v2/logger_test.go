@@ -0,0 +1,80 @@
+package sigolo
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestLoggerFormatIsNotAppliedTwice(t *testing.T) {
+	logger, buffer := newBufferLogger(LOG_INFO)
+
+	logger.Infof("%s", "100%d")
+
+	assertTrue(t, strings.HasSuffix(buffer.String(), "| 100%d\n"))
+}
+
+func TestLoggerUsesOwnLogLevel(t *testing.T) {
+	SetDefaultLogLevel(LOG_ERROR)
+	defer SetDefaultLogLevel(LOG_INFO)
+	logger, buffer := newBufferLogger(LOG_DEBUG)
+
+	logger.Debug("foo")
+
+	assertTrue(t, strings.HasSuffix(buffer.String(), "| foo\n"))
+}
+
+func TestLoggerLazyArguments(t *testing.T) {
+	logger, buffer := newBufferLogger(LOG_INFO)
+	calls := 0
+	lazyValue := func() string {
+		calls++
+		return "lazy"
+	}
+
+	logger.Debugf("value: %s", lazyValue)
+	assertTrue(t, calls == 0)
+	assertTrue(t, buffer.Len() == 0)
+
+	logger.Infof("value: %s %d", lazyValue, 123)
+	assertTrue(t, calls == 1)
+	assertTrue(t, strings.HasSuffix(buffer.String(), "| value: lazy 123\n"))
+}
+
+func TestLoggerDisabledLevelsDoNotAllocate(t *testing.T) {
+	logger, _ := newBufferLogger(LOG_ERROR)
+
+	allocations := testing.AllocsPerRun(100, func() {
+		logger.Debug("foo")
+		logger.Debugf("foo %s %d", "bar", 123)
+		logger.Infob(1, "foo %s", expensiveValue)
+		logger.Warnf("foo %s", expensiveValue)
+	})
+
+	if allocations != 0 {
+		t.Errorf("Expected no allocations but got %f", allocations)
+	}
+}
+
+func TestLoggerStackIsRecorded(t *testing.T) {
+	logger, buffer := newBufferLogger(LOG_FATAL)
+	logger.Recorder = NewFlightRecorder(10)
+
+	logger.Stack(errors.New("foo"))
+	assertTrue(t, buffer.Len() == 0)
+
+	logger.DumpRecent()
+	assertTrue(t, strings.Contains(buffer.String(), "[ERROR]"))
+	assertTrue(t, strings.Contains(buffer.String(), "logger_test.go"))
+	assertTrue(t, strings.HasSuffix(buffer.String(), "| foo\n"))
+}
+
+func TestSetLogLevelDoesNotChangeCopies(t *testing.T) {
+	logger, _ := newBufferLogger(LOG_INFO)
+	other := *logger
+
+	other.SetLogLevel(LOG_TRACE)
+
+	assertTrue(t, other.LogLevel == LOG_TRACE)
+	assertTrue(t, logger.LogLevel == LOG_INFO)
+}
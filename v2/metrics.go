@@ -0,0 +1,248 @@
+package sigolo
+
+import (
+	"expvar"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Metrics counts the written entries per level and, when enabled, per caller. It also counts the entries dropped by
+// samplers, throttles, deduplication and vetoing hooks. The counters are available in the Prometheus text format by
+// using Metrics as http.Handler and via expvar, see PublishExpvar. The zero value is ready to use.
+type Metrics struct {
+	// PerCaller enables counting the entries per caller and level. Be aware that each call site creates a new time series.
+	PerCaller bool
+
+	entries [levelCount]atomic.Uint64
+	dropped [dropReasonCount][levelCount]atomic.Uint64
+
+	mutex         sync.Mutex
+	callerEntries map[callerMetricKey]uint64
+}
+
+// DropReason is the reason why an entry of an enabled level wasn't written.
+type DropReason int
+
+const (
+	// DROP_SAMPLER is used for entries dropped by the Sampler of the logger.
+	DROP_SAMPLER DropReason = iota
+	// DROP_THROTTLE is used for entries suppressed by Every(...), EveryDuration(...) or Once().
+	DROP_THROTTLE
+	// DROP_DEDUPLICATION is used for repeated entries collapsed by the deduplication.
+	DROP_DEDUPLICATION
+	// DROP_HOOK is used for entries vetoed by a hook.
+	DROP_HOOK
+)
+
+const dropReasonCount = int(DROP_HOOK) + 1
+
+func (r DropReason) String() string {
+	switch r {
+	case DROP_SAMPLER:
+		return "sampler"
+	case DROP_THROTTLE:
+		return "throttle"
+	case DROP_DEDUPLICATION:
+		return "deduplication"
+	case DROP_HOOK:
+		return "hook"
+	}
+	return "unknown"
+}
+
+type callerMetricKey struct {
+	level  Level
+	caller string
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{}
+}
+
+// EnableMetrics counts the entries written and dropped by this logger and its copies, e.g. spans.
+func (l *Logger) EnableMetrics(m *Metrics) {
+	l.Metrics = m
+}
+
+// EnableDefaultMetrics counts the entries written and dropped by the default logger. Use nil to disable it.
+func EnableDefaultMetrics(m *Metrics) {
+	metrics = m
+	DefaultLogger = GetLoggerWithCurrentDefaults()
+}
+
+// Entries returns the number of written entries of the given level.
+func (m *Metrics) Entries(level Level) uint64 {
+	if level < LOG_PLAIN || level > LOG_FATAL {
+		return 0
+	}
+	return m.entries[level].Load()
+}
+
+// Dropped returns the number of entries of the given level, which were dropped for the given reason.
+func (m *Metrics) Dropped(level Level, reason DropReason) uint64 {
+	if level < LOG_PLAIN || level > LOG_FATAL || reason < 0 || int(reason) >= dropReasonCount {
+		return 0
+	}
+	return m.dropped[reason][level].Load()
+}
+
+// count is called for each written entry.
+func (m *Metrics) count(entry *Entry) {
+	if entry.Level < LOG_PLAIN || entry.Level > LOG_FATAL {
+		return
+	}
+	m.entries[entry.Level].Add(1)
+
+	if m.PerCaller {
+		m.mutex.Lock()
+		if m.callerEntries == nil {
+			m.callerEntries = map[callerMetricKey]uint64{}
+		}
+		m.callerEntries[callerMetricKey{level: entry.Level, caller: entry.Caller}]++
+		m.mutex.Unlock()
+	}
+}
+
+// drop is called for each entry of an enabled level, which isn't written for the given reason.
+func (m *Metrics) drop(level Level, reason DropReason) {
+	if level < LOG_PLAIN || level > LOG_FATAL {
+		return
+	}
+	m.dropped[reason][level].Add(1)
+}
+
+// ServeHTTP writes the metrics in the Prometheus text exposition format.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write(m.appendPrometheus(nil))
+}
+
+func (m *Metrics) appendPrometheus(buffer []byte) []byte {
+	buffer = append(buffer, "# HELP sigolo_entries_total Number of log entries per level.\n"...)
+	buffer = append(buffer, "# TYPE sigolo_entries_total counter\n"...)
+	for level := LOG_PLAIN; level <= LOG_FATAL; level++ {
+		buffer = appendPrometheusSample(buffer, "sigolo_entries_total", m.Entries(level), "level", levelLabel(level))
+	}
+
+	buffer = append(buffer, "# HELP sigolo_dropped_entries_total Number of log entries dropped per level and reason.\n"...)
+	buffer = append(buffer, "# TYPE sigolo_dropped_entries_total counter\n"...)
+	for reason := DROP_SAMPLER; int(reason) < dropReasonCount; reason++ {
+		for level := LOG_PLAIN; level <= LOG_FATAL; level++ {
+			buffer = appendPrometheusSample(buffer, "sigolo_dropped_entries_total", m.Dropped(level, reason), "level", levelLabel(level), "reason", reason.String())
+		}
+	}
+
+	if m.PerCaller {
+		buffer = append(buffer, "# HELP sigolo_caller_entries_total Number of log entries per caller and level.\n"...)
+		buffer = append(buffer, "# TYPE sigolo_caller_entries_total counter\n"...)
+		for _, key := range m.sortedCallerKeys() {
+			m.mutex.Lock()
+			value := m.callerEntries[key]
+			m.mutex.Unlock()
+			buffer = appendPrometheusSample(buffer, "sigolo_caller_entries_total", value, "level", levelLabel(key.level), "caller", key.caller)
+		}
+	}
+
+	return buffer
+}
+
+func (m *Metrics) sortedCallerKeys() []callerMetricKey {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	keys := make([]callerMetricKey, 0, len(m.callerEntries))
+	for key := range m.callerEntries {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].caller != keys[j].caller {
+			return keys[i].caller < keys[j].caller
+		}
+		return keys[i].level < keys[j].level
+	})
+	return keys
+}
+
+// appendPrometheusSample appends one line like `name{label="value"} 123`. The labels are given as name-value pairs.
+func appendPrometheusSample(buffer []byte, name string, value uint64, labels ...string) []byte {
+	buffer = append(buffer, name...)
+	buffer = append(buffer, '{')
+	for i := 0; i+1 < len(labels); i += 2 {
+		if i > 0 {
+			buffer = append(buffer, ',')
+		}
+		buffer = append(buffer, labels[i]...)
+		buffer = append(buffer, `="`...)
+		buffer = appendPrometheusLabelValue(buffer, labels[i+1])
+		buffer = append(buffer, '"')
+	}
+	buffer = append(buffer, "} "...)
+	buffer = strconv.AppendUint(buffer, value, 10)
+	return append(buffer, '\n')
+}
+
+func appendPrometheusLabelValue(buffer []byte, value string) []byte {
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			buffer = append(buffer, `\\`...)
+		case '"':
+			buffer = append(buffer, `\"`...)
+		case '\n':
+			buffer = append(buffer, `\n`...)
+		default:
+			buffer = append(buffer, value[i])
+		}
+	}
+	return buffer
+}
+
+func levelLabel(level Level) string {
+	return strings.ToLower(level.String())
+}
+
+// PublishExpvar publishes the metrics under the given name, so that they are served by the expvar handler. Like
+// expvar.Publish, this panics when the name is already in use.
+func (m *Metrics) PublishExpvar(name string) {
+	expvar.Publish(name, expvar.Func(m.expvarValue))
+}
+
+// expvarValue returns the counters in the structure {"entries": {...}, "dropped": {"sampler": {...}, ...},
+// "callers": {...}}.
+func (m *Metrics) expvarValue() interface{} {
+	entries := map[string]uint64{}
+	for level := LOG_PLAIN; level <= LOG_FATAL; level++ {
+		entries[levelLabel(level)] = m.Entries(level)
+	}
+	dropped := map[string]map[string]uint64{}
+	for reason := DROP_SAMPLER; int(reason) < dropReasonCount; reason++ {
+		dropped[reason.String()] = map[string]uint64{}
+		for level := LOG_PLAIN; level <= LOG_FATAL; level++ {
+			dropped[reason.String()][levelLabel(level)] = m.Dropped(level, reason)
+		}
+	}
+
+	value := map[string]interface{}{
+		"entries": entries,
+		"dropped": dropped,
+	}
+
+	if m.PerCaller {
+		callers := map[string]map[string]uint64{}
+		m.mutex.Lock()
+		for key, count := range m.callerEntries {
+			if callers[key.caller] == nil {
+				callers[key.caller] = map[string]uint64{}
+			}
+			callers[key.caller][levelLabel(key.level)] = count
+		}
+		m.mutex.Unlock()
+		value["callers"] = callers
+	}
+
+	return value
+}
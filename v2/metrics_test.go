@@ -0,0 +1,128 @@
+package sigolo
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMetrics(t *testing.T) {
+	logger, _ := newBufferLogger(LOG_DEBUG)
+	logger.Sampler = NewSampler(map[Level]float64{LOG_TRACE: 0})
+	logger.LogLevel = LOG_TRACE
+	metrics := NewMetrics()
+	metrics.PerCaller = true
+	logger.EnableMetrics(metrics)
+	// Hooks added afterwards may still veto entries, which are then not counted as written
+	logger.AddHook(func(entry *Entry) bool {
+		return entry.Message != "vetoed"
+	})
+
+	for i := 0; i < 3; i++ {
+		logger.Info("foo")
+	}
+	logger.Error("bar")
+	logger.Trace("dropped")
+	logger.Warn("vetoed")
+
+	assertTrue(t, metrics.Entries(LOG_INFO) == 3)
+	assertTrue(t, metrics.Entries(LOG_ERROR) == 1)
+	assertTrue(t, metrics.Entries(LOG_TRACE) == 0)
+	assertTrue(t, metrics.Entries(LOG_WARN) == 0)
+	assertTrue(t, metrics.Dropped(LOG_TRACE, DROP_SAMPLER) == 1)
+	assertTrue(t, metrics.Dropped(LOG_WARN, DROP_HOOK) == 1)
+
+	recorder := httptest.NewRecorder()
+	metrics.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	body := recorder.Body.String()
+
+	assertTrue(t, strings.HasPrefix(recorder.Header().Get("Content-Type"), "text/plain; version=0.0.4"))
+	assertTrue(t, strings.Contains(body, "# TYPE sigolo_entries_total counter\n"))
+	assertTrue(t, strings.Contains(body, "sigolo_entries_total{level=\"info\"} 3\n"))
+	assertTrue(t, strings.Contains(body, "sigolo_entries_total{level=\"debug\"} 0\n"))
+	assertTrue(t, strings.Contains(body, "sigolo_dropped_entries_total{level=\"trace\",reason=\"sampler\"} 1\n"))
+	assertTrue(t, strings.Contains(body, "sigolo_caller_entries_total{level=\"error\",caller=\"metrics_test.go:"))
+}
+
+func TestMetricsCountDroppedEntries(t *testing.T) {
+	logger, _ := newThrottleTestLogger()
+	metrics := NewMetrics()
+	logger.EnableMetrics(metrics)
+	logger.SetDeduplicationWindow(time.Hour)
+
+	for i := 0; i < 3; i++ {
+		logger.Once().Warn("throttled")
+	}
+	for i := 0; i < 3; i++ {
+		logger.Info("repeated")
+	}
+	logger.Info("other")
+
+	assertTrue(t, metrics.Dropped(LOG_WARN, DROP_THROTTLE) == 2)
+	assertTrue(t, metrics.Dropped(LOG_INFO, DROP_DEDUPLICATION) == 2)
+	assertTrue(t, metrics.Entries(LOG_WARN) == 1)
+	// "repeated", "Last message repeated 2 times" and "other"
+	assertTrue(t, metrics.Entries(LOG_INFO) == 3)
+}
+
+func TestBufferedMetricsCountOnlyWrittenEntries(t *testing.T) {
+	logger, _ := newBufferedTestLogger(10)
+	metrics := NewMetrics()
+	logger.EnableMetrics(metrics)
+
+	logger.Debug("debug")
+	logger.Info("info")
+	assertTrue(t, metrics.Entries(LOG_INFO) == 0)
+
+	logger.Discard()
+
+	assertTrue(t, metrics.Entries(LOG_DEBUG) == 0)
+	assertTrue(t, metrics.Entries(LOG_INFO) == 1)
+}
+
+// expvarTestMetrics is published only once, because published variables can't be removed again.
+var (
+	expvarTestMetrics     = NewMetrics()
+	expvarTestMetricsOnce sync.Once
+)
+
+func TestMetricsZeroValue(t *testing.T) {
+	logger, _ := newBufferLogger(LOG_INFO)
+	metrics := &Metrics{PerCaller: true}
+	logger.EnableMetrics(metrics)
+
+	logger.Info("foo")
+
+	assertTrue(t, metrics.Entries(LOG_INFO) == 1)
+	assertTrue(t, strings.Contains(string(metrics.appendPrometheus(nil)), `sigolo_caller_entries_total{level="info",caller="metrics_test.go:`))
+}
+
+func TestMetricsExpvar(t *testing.T) {
+	expvarTestMetricsOnce.Do(func() {
+		expvarTestMetrics.PublishExpvar("sigolo_test")
+	})
+	warnings := expvarTestMetrics.Entries(LOG_WARN)
+	expvarTestMetrics.count(&Entry{Level: LOG_WARN})
+
+	var value struct {
+		Entries map[string]uint64            `json:"entries"`
+		Dropped map[string]map[string]uint64 `json:"dropped"`
+	}
+	err := json.Unmarshal([]byte(expvar.Get("sigolo_test").String()), &value)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertTrue(t, value.Entries["warn"] == warnings+1)
+	assertTrue(t, value.Dropped["sampler"]["warn"] == 0)
+}
+
+func TestPrometheusLabelEscaping(t *testing.T) {
+	line := string(appendPrometheusSample(nil, "foo", 1, "caller", "a\"b\\c\nd"))
+
+	assertTrue(t, line == `foo{caller="a\"b\\c\nd"} 1`+"\n")
+}
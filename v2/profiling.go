@@ -0,0 +1,49 @@
+package sigolo
+
+import (
+	"context"
+	"runtime/pprof"
+	"runtime/trace"
+)
+
+// Labels of the profiler set by DoWithLabels.
+const (
+	TraceIdLabel   = "trace_id"
+	SpanIdLabel    = "span_id"
+	ComponentLabel = "component"
+)
+
+// SetDefaultRuntimeTrace enables emitting the entries of the default logger via runtime/trace.Log, see
+// Logger.RuntimeTrace.
+func SetDefaultRuntimeTrace(enabled bool) {
+	runtimeTrace = enabled
+	DefaultLogger = GetLoggerWithCurrentDefaults()
+}
+
+// logRuntimeTrace emits the message with the level as category when an execution trace is being recorded.
+func logRuntimeTrace(level Level, message string) {
+	if trace.IsEnabled() {
+		trace.Log(context.Background(), level.String(), message)
+	}
+}
+
+// ProfilerLabels returns the labels used by DoWithLabels.
+func (l *Logger) ProfilerLabels(component string) pprof.LabelSet {
+	if l.LogSpanId != 0 {
+		return pprof.Labels(TraceIdLabel, l.LogTraceId.String(), SpanIdLabel, l.LogSpanId.String(), ComponentLabel, component)
+	}
+	return pprof.Labels(TraceIdLabel, l.LogTraceId.String(), ComponentLabel, component)
+}
+
+// DoWithLabels calls the function with the profiler labels "trace_id", "component" and, if the logger has a span,
+// "span_id", so that CPU profiles can be attributed to trace IDs. Goroutines started by the function inherit the labels.
+func (l *Logger) DoWithLabels(ctx context.Context, component string, function func(ctx context.Context)) {
+	pprof.Do(ctx, l.ProfilerLabels(component), function)
+}
+
+// DoWithLabels calls the function with profiler labels of the DefaultLogger, see Logger.DoWithLabels for details.
+func DoWithLabels(ctx context.Context, component string, function func(ctx context.Context)) {
+	logger := *DefaultLogger
+	increaseTraceId()
+	logger.DoWithLabels(ctx, component, function)
+}
@@ -0,0 +1,52 @@
+package sigolo
+
+import (
+	"bytes"
+	"context"
+	"runtime/pprof"
+	"runtime/trace"
+	"testing"
+)
+
+func TestRuntimeTrace(t *testing.T) {
+	logger, buffer := newBufferLogger(LOG_INFO)
+	logger.RuntimeTrace = true
+
+	// Nothing happens while no execution trace is recorded
+	logger.Info("not traced")
+
+	output := &bytes.Buffer{}
+	if err := trace.Start(output); err != nil {
+		t.Skipf("Execution trace not available: %v", err)
+	}
+	logger.Warn("traced entry")
+	trace.Stop()
+
+	assertTrue(t, bytes.Contains(output.Bytes(), []byte("traced entry")))
+	assertTrue(t, bytes.Contains(output.Bytes(), []byte("WARN")))
+	assertFalse(t, bytes.Contains(output.Bytes(), []byte("not traced")))
+	assertTrue(t, bytes.Count(buffer.Bytes(), []byte("\n")) == 2)
+}
+
+func TestDoWithLabels(t *testing.T) {
+	logger, _ := newBufferLogger(LOG_INFO)
+	logger.LogTraceId = SequentialTraceId(0x2a)
+
+	called := false
+	logger.DoWithLabels(context.Background(), "importer", func(ctx context.Context) {
+		called = true
+		traceId, _ := pprof.Label(ctx, TraceIdLabel)
+		component, _ := pprof.Label(ctx, ComponentLabel)
+		_, hasSpan := pprof.Label(ctx, SpanIdLabel)
+		assertTrue(t, traceId == "2a")
+		assertTrue(t, component == "importer")
+		assertFalse(t, hasSpan)
+	})
+	assertTrue(t, called)
+
+	span := logger.Span("load")
+	span.DoWithLabels(context.Background(), "importer", func(ctx context.Context) {
+		spanId, _ := pprof.Label(ctx, SpanIdLabel)
+		assertTrue(t, spanId == span.LogSpanId.String())
+	})
+}
@@ -0,0 +1,131 @@
+package sigolo
+
+import (
+	"sync"
+	"time"
+)
+
+// FlightRecorder keeps the most recent entries of all levels, even of the ones disabled by the log level. When an entry
+// with at least the DumpLevel is logged, the recorded entries, which haven't been written yet, are written before it.
+// This shows what happened right before an error without having to log everything all the time.
+//
+// Entries are not formatted while recording. Arguments are therefore formatted when the entries are dumped and show
+// the state of the arguments at that time.
+type FlightRecorder struct {
+	// DumpLevel is the minimum level of an entry causing the recorded entries to be dumped. Default is LOG_ERROR.
+	DumpLevel Level
+
+	mutex   sync.Mutex
+	entries []recordedEntry
+	next    int
+	count   int
+}
+
+type recordedEntry struct {
+	time           time.Time
+	level          Level
+	programCounter uintptr
+	traceId        TraceId
+	spanId         SpanId
+	format         string
+	args           []interface{}
+	isFormat       bool
+	// written is true when the entry was written when it was logged and therefore doesn't need to be dumped.
+	written bool
+}
+
+// NewFlightRecorder creates a recorder keeping the given number of recent entries.
+func NewFlightRecorder(size int) *FlightRecorder {
+	if size < 1 {
+		size = 1
+	}
+	return &FlightRecorder{
+		DumpLevel: LOG_ERROR,
+		entries:   make([]recordedEntry, size),
+	}
+}
+
+// DumpRecent writes the recent entries of the flight recorder, which haven't been written yet.
+func (l *Logger) DumpRecent() {
+	if l.Recorder == nil {
+		return
+	}
+	l.Recorder.dump(l)
+}
+
+func (r *FlightRecorder) record(now time.Time, level Level, programCounter uintptr, traceId TraceId, spanId SpanId, format string, args []interface{}, isFormat bool, written bool) {
+	if written && level >= r.DumpLevel {
+		// This entry causes a dump and would only take the place of an older entry.
+		return
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	entry := &r.entries[r.next]
+	entry.time = now
+	entry.level = level
+	entry.programCounter = programCounter
+	entry.traceId = traceId
+	entry.spanId = spanId
+	entry.format = format
+	// Copy the arguments, as the slice belongs to the caller. This also reuses the slice of the previous entry.
+	entry.args = append(entry.args[:0], args...)
+	entry.isFormat = isFormat
+	entry.written = written
+
+	r.next = (r.next + 1) % len(r.entries)
+	if r.count < len(r.entries) {
+		r.count++
+	}
+}
+
+// dump writes all recorded entries, which haven't been written yet, using the given logger and clears the recorder. The
+// entries are written after unlocking the recorder, as observers and hooks may log with the same logger.
+func (r *FlightRecorder) dump(logger *Logger) {
+	for _, entry := range r.take() {
+		message := entry.format
+		if entry.isFormat {
+			message = formatMessage(entry.format, entry.args)
+		}
+
+		dumpedEntry := Entry{
+			Time:    entry.time,
+			Level:   entry.level,
+			Caller:  formatCallerOf(entry.programCounter, logger.CallerFormat),
+			TraceId: entry.traceId,
+			SpanId:  entry.spanId,
+			Message: message,
+		}
+		if logger.Redactor != nil {
+			logger.Redactor.redactEntry(&dumpedEntry)
+		}
+		logger.write(&dumpedEntry)
+	}
+}
+
+// take returns the recorded entries, which haven't been written yet, and clears the recorder.
+func (r *FlightRecorder) take() []recordedEntry {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var entries []recordedEntry
+	first := (r.next - r.count + len(r.entries)) % len(r.entries)
+	for i := 0; i < r.count; i++ {
+		entry := &r.entries[(first+i)%len(r.entries)]
+		if !entry.written {
+			taken := *entry
+			taken.args = append([]interface{}(nil), entry.args...)
+			entries = append(entries, taken)
+		}
+
+		// Don't keep references to the arguments any longer than necessary.
+		for j := range entry.args {
+			entry.args[j] = nil
+		}
+		entry.args = entry.args[:0]
+	}
+
+	r.count = 0
+	return entries
+}
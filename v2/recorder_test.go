@@ -0,0 +1,97 @@
+package sigolo
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFlightRecorderDumpsOnError(t *testing.T) {
+	logger, buffer := newBufferLogger(LOG_INFO)
+	logger.Recorder = NewFlightRecorder(3)
+
+	for i := 0; i < 5; i++ {
+		logger.Debugf("debug %d", i)
+	}
+	logger.Info("info")
+	assertTrue(t, strings.Count(buffer.String(), "\n") == 1)
+
+	logger.Error("error")
+
+	lines := strings.Split(strings.TrimSuffix(buffer.String(), "\n"), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("Expected 4 lines but got %d: %q", len(lines), buffer.String())
+	}
+	assertTrue(t, strings.HasSuffix(lines[0], "| info"))
+	assertTrue(t, strings.Contains(lines[1], "[DEBUG] recorder_test.go:"))
+	assertTrue(t, strings.HasSuffix(lines[1], "| debug 3"))
+	assertTrue(t, strings.HasSuffix(lines[2], "| debug 4"))
+	assertTrue(t, strings.HasSuffix(lines[3], "| error"))
+
+	// Dumped entries are not dumped again
+	buffer.Reset()
+	logger.Error("error")
+	assertTrue(t, strings.Count(buffer.String(), "\n") == 1)
+}
+
+func TestFlightRecorderDumpRecent(t *testing.T) {
+	logger, buffer := newBufferLogger(LOG_INFO)
+	logger.Recorder = NewFlightRecorder(10)
+
+	logger.Trace("trace")
+	logger.Debugf("lazy %s", func() string { return "debug" })
+	assertTrue(t, buffer.Len() == 0)
+
+	logger.DumpRecent()
+
+	lines := strings.Split(strings.TrimSuffix(buffer.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines but got %d: %q", len(lines), buffer.String())
+	}
+	assertTrue(t, strings.HasSuffix(lines[0], "| trace"))
+	assertTrue(t, strings.HasSuffix(lines[1], "| lazy debug"))
+}
+
+func TestFlightRecorderDumpKeepsSpanId(t *testing.T) {
+	logger, buffer := newBufferLogger(LOG_INFO)
+	logger.LogTraceId = SequentialTraceId(0x2a)
+	logger.Recorder = NewFlightRecorder(10)
+
+	span := logger.Span("load")
+	span.Debug("inside")
+	logger.Error("outside")
+
+	lines := strings.Split(strings.TrimSuffix(buffer.String(), "\n"), "\n")
+	assertTrue(t, strings.HasSuffix(lines[1], "#2a/"+span.LogSpanId.String()+" | inside"))
+	assertTrue(t, strings.HasSuffix(lines[2], "#2a | outside"))
+}
+
+func TestFlightRecorderObserverCanLogWhileDumping(t *testing.T) {
+	logger, buffer := newBufferLogger(LOG_INFO)
+	logger.Recorder = NewFlightRecorder(10)
+	logger.AddObserver(func(entry *Entry) {
+		if entry.Level == LOG_DEBUG {
+			logger.Error("observed")
+		}
+	})
+
+	logger.Debug("debug")
+	logger.Error("error")
+
+	assertTrue(t, strings.Contains(buffer.String(), "| debug\n"))
+	assertTrue(t, strings.Contains(buffer.String(), "| observed\n"))
+	assertTrue(t, strings.HasSuffix(buffer.String(), "| error\n"))
+}
+
+func TestFlightRecorderDisabledLevelsDoNotAllocate(t *testing.T) {
+	logger, _ := newBufferLogger(LOG_ERROR)
+	logger.Recorder = NewFlightRecorder(10)
+	logger.Debugf("foo %s %d", "bar", 123)
+
+	allocations := testing.AllocsPerRun(100, func() {
+		logger.Debugf("foo %s %d", "bar", 123)
+	})
+
+	if allocations != 0 {
+		t.Errorf("Expected no allocations but got %f", allocations)
+	}
+}
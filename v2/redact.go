@@ -0,0 +1,170 @@
+package sigolo
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// RedactionReplacement replaces redacted secrets.
+const RedactionReplacement = "[REDACTED]"
+
+// Redacted wraps a secret value, e.g. a password, so that it can be passed to log functions without leaking it. All
+// formatting verbs print RedactionReplacement instead of the value.
+type Redacted string
+
+func (r Redacted) String() string {
+	return RedactionReplacement
+}
+
+func (r Redacted) GoString() string {
+	return RedactionReplacement
+}
+
+func (r Redacted) Format(state fmt.State, verb rune) {
+	state.Write([]byte(RedactionReplacement))
+}
+
+func (r Redacted) MarshalText() ([]byte, error) {
+	return []byte(RedactionReplacement), nil
+}
+
+// redactionPattern is a pattern of secrets. When the expression has a group named "secret", only this group is
+// replaced, otherwise the whole match.
+type redactionPattern struct {
+	expression *regexp.Regexp
+	// isSecret further checks a match, e.g. the checksum of a credit card number. Nil accepts all matches.
+	isSecret func(match string) bool
+}
+
+// Redactor removes secrets from messages and field values before the entry is formatted.
+type Redactor struct {
+	patterns     []redactionPattern
+	deniedFields map[string]bool
+}
+
+// NewRedactor creates a redactor with built-in patterns for bearer tokens, AWS keys, JSON web tokens, credit card
+// numbers and key-value pairs like "password=...". Fields with names like "password" or "token" are redacted
+// completely.
+func NewRedactor() *Redactor {
+	redactor := &Redactor{
+		deniedFields: map[string]bool{},
+	}
+
+	redactor.AddPattern(regexp.MustCompile(`(?i)\bbearer\s+(?P<secret>[A-Za-z0-9\-._~+/]+=*)`))
+	redactor.AddPattern(regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`))
+	redactor.AddPattern(regexp.MustCompile(`(?i)\baws_?secret_?access_?key["']?\s*[=:]\s*["']?(?P<secret>[A-Za-z0-9/+=]{40})`))
+	redactor.AddPattern(regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`))
+	redactor.patterns = append(redactor.patterns, redactionPattern{
+		expression: regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`),
+		isSecret:   isLuhnValid,
+	})
+	redactor.AddPattern(regexp.MustCompile(`(?i)\b(?:password|passwd|pwd|secret|token|api[_-]?key|access[_-]?key)["']?\s*[=:]\s*(?P<secret>"[^"]*"|'[^']*'|[^\s"'&,;]+)`))
+
+	redactor.DenyFields("password", "passwd", "secret", "token", "authorization", "api_key", "apikey", "cookie")
+
+	return redactor
+}
+
+// AddPattern adds an expression matching secrets. When the expression has a group named "secret", only this group is
+// replaced, otherwise the whole match.
+func (r *Redactor) AddPattern(expression *regexp.Regexp) {
+	r.patterns = append(r.patterns, redactionPattern{expression: expression})
+}
+
+// DenyFields redacts the values of the fields with the given names completely. The names are case-insensitive.
+func (r *Redactor) DenyFields(names ...string) {
+	for _, name := range names {
+		r.deniedFields[strings.ToLower(name)] = true
+	}
+}
+
+// Redact replaces all secrets within the text.
+func (r *Redactor) Redact(text string) string {
+	for _, pattern := range r.patterns {
+		text = pattern.redact(text)
+	}
+	return text
+}
+
+// redactEntry redacts the message and the field values of the entry. The fields are copied when they need to be
+// changed, because they might be shared with the caller.
+func (r *Redactor) redactEntry(entry *Entry) {
+	entry.Message = r.Redact(entry.Message)
+	r.redactFields(entry)
+}
+
+// redactFields redacts the field values of the entry, see redactEntry.
+func (r *Redactor) redactFields(entry *Entry) {
+	copied := false
+	for i, field := range entry.Fields {
+		var value interface{}
+		if r.deniedFields[strings.ToLower(field.Key)] {
+			value = RedactionReplacement
+		} else {
+			text := fmt.Sprint(field.Value)
+			redactedText := r.Redact(text)
+			if redactedText == text {
+				continue
+			}
+			value = redactedText
+		}
+
+		if !copied {
+			entry.Fields = append([]Field(nil), entry.Fields...)
+			copied = true
+		}
+		entry.Fields[i].Value = value
+	}
+}
+
+func (p *redactionPattern) redact(text string) string {
+	matches := p.expression.FindAllStringSubmatchIndex(text, -1)
+	if matches == nil {
+		return text
+	}
+
+	secretGroup := p.expression.SubexpIndex("secret")
+	builder := strings.Builder{}
+	lastEnd := 0
+	for _, match := range matches {
+		start, end := match[0], match[1]
+		if secretGroup > 0 && match[2*secretGroup] >= 0 {
+			start, end = match[2*secretGroup], match[2*secretGroup+1]
+		}
+
+		if p.isSecret != nil && !p.isSecret(text[start:end]) {
+			continue
+		}
+
+		builder.WriteString(text[lastEnd:start])
+		builder.WriteString(RedactionReplacement)
+		lastEnd = end
+	}
+	builder.WriteString(text[lastEnd:])
+
+	return builder.String()
+}
+
+// isLuhnValid checks the checksum of credit card numbers. Spaces and dashes are ignored.
+func isLuhnValid(number string) bool {
+	sum := 0
+	digits := 0
+	for i := len(number) - 1; i >= 0; i-- {
+		c := number[i]
+		if c == ' ' || c == '-' {
+			continue
+		}
+
+		digit := int(c - '0')
+		if digits%2 == 1 {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+		digits++
+	}
+	return digits >= 13 && sum%10 == 0
+}
@@ -0,0 +1,98 @@
+package sigolo
+
+import (
+	"math"
+	"math/bits"
+	"sync/atomic"
+)
+
+// levelCount is the number of log levels, which can be used for arrays indexed by a level.
+const levelCount = int(LOG_FATAL) + 1
+
+// Sampler decides which entries of a level are kept. The decision is based on a hash of the trace ID, so either all or
+// none of the entries of one trace ID are kept. Entries with level WARN and above are always kept.
+type Sampler struct {
+	// thresholds contains the maximum hash value of kept entries per level.
+	thresholds [levelCount]uint64
+	dropped    [levelCount]atomic.Uint64
+}
+
+// NewSampler creates a sampler keeping the given fraction (between 0 and 1) of the trace IDs for each level. Levels not
+// contained in the map are kept completely.
+//
+// Example: Keep 1% of the traces for DEBUG and TRACE:
+//
+//	logger.Sampler = sigolo.NewSampler(map[sigolo.Level]float64{
+//		sigolo.LOG_TRACE: 0.01,
+//		sigolo.LOG_DEBUG: 0.01,
+//	})
+func NewSampler(rates map[Level]float64) *Sampler {
+	sampler := &Sampler{}
+	for level := range sampler.thresholds {
+		sampler.thresholds[level] = math.MaxUint64
+	}
+
+	for level, rate := range rates {
+		if level < LOG_PLAIN || level >= LOG_WARN {
+			continue
+		}
+
+		switch {
+		case rate <= 0:
+			sampler.thresholds[level] = 0
+		case rate < 1:
+			// Converting a float64 beyond the range of uint64 is undefined, so clamp it to be safe for rates close to 1.
+			threshold := rate * math.MaxUint64
+			if threshold < math.MaxUint64 {
+				sampler.thresholds[level] = uint64(threshold)
+			}
+		}
+	}
+
+	return sampler
+}
+
+// Keep returns true when the entry of the given level and trace ID should be logged. Dropped entries are counted.
+func (s *Sampler) Keep(level Level, traceId TraceId) bool {
+	if level >= LOG_WARN || level < LOG_PLAIN {
+		return true
+	}
+
+	threshold := s.thresholds[level]
+	if threshold == math.MaxUint64 {
+		return true
+	}
+
+	if hashTraceId(traceId) < threshold {
+		return true
+	}
+
+	s.dropped[level].Add(1)
+	return false
+}
+
+// Dropped returns the number of dropped entries of the given level.
+func (s *Sampler) Dropped(level Level) uint64 {
+	if level < LOG_PLAIN || level > LOG_FATAL {
+		return 0
+	}
+	return s.dropped[level].Load()
+}
+
+// DroppedTotal returns the number of dropped entries of all levels.
+func (s *Sampler) DroppedTotal() uint64 {
+	var total uint64
+	for level := range s.dropped {
+		total += s.dropped[level].Load()
+	}
+	return total
+}
+
+// hashTraceId spreads the trace IDs, which are usually consecutive numbers, evenly over the range of uint64 values. This
+// is the finalizer of the SplitMix64 generator.
+func hashTraceId(traceId TraceId) uint64 {
+	hash := traceId.Low ^ bits.RotateLeft64(traceId.High, 32)
+	hash = (hash ^ (hash >> 30)) * 0xbf58476d1ce4e5b9
+	hash = (hash ^ (hash >> 27)) * 0x94d049bb133111eb
+	return hash ^ (hash >> 31)
+}
@@ -0,0 +1,71 @@
+package sigolo
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestSamplerIsConsistentPerTraceId(t *testing.T) {
+	sampler := NewSampler(map[Level]float64{
+		LOG_TRACE: 0.5,
+		LOG_DEBUG: 0.5,
+	})
+
+	for i := uint64(0); i < 100; i++ {
+		traceId := SequentialTraceId(i)
+		keepDebug := sampler.Keep(LOG_DEBUG, traceId)
+		assertTrue(t, keepDebug == sampler.Keep(LOG_DEBUG, traceId))
+		assertTrue(t, keepDebug == sampler.Keep(LOG_TRACE, traceId))
+	}
+}
+
+func TestSamplerRate(t *testing.T) {
+	sampler := NewSampler(map[Level]float64{
+		LOG_DEBUG: 0.01,
+		LOG_INFO:  0,
+		LOG_WARN:  0,
+	})
+
+	kept := 0
+	for i := uint64(0); i < 100000; i++ {
+		traceId := SequentialTraceId(i)
+		if sampler.Keep(LOG_DEBUG, traceId) {
+			kept++
+		}
+	}
+
+	if kept < 800 || kept > 1200 {
+		t.Errorf("Expected around 1000 kept entries but got %d", kept)
+	}
+	assertTrue(t, sampler.Dropped(LOG_DEBUG) == uint64(100000-kept))
+
+	assertFalse(t, sampler.Keep(LOG_INFO, SequentialTraceId(1)))
+	assertTrue(t, sampler.Keep(LOG_TRACE, SequentialTraceId(1)))
+	assertTrue(t, sampler.Keep(LOG_WARN, SequentialTraceId(1)))
+	assertTrue(t, sampler.Keep(LOG_ERROR, SequentialTraceId(1)))
+	assertTrue(t, sampler.DroppedTotal() == uint64(100000-kept+1))
+}
+
+func TestLoggerWithSampler(t *testing.T) {
+	logger, buffer := newBufferLogger(LOG_DEBUG)
+	logger.Sampler = NewSampler(map[Level]float64{
+		LOG_DEBUG: 0,
+	})
+
+	logger.Debugf("foo %d", 1)
+	logger.Debug("foo")
+	logger.Writer(LOG_DEBUG).Write([]byte("foo\n"))
+	logger.Warn("bar")
+
+	assertTrue(t, strings.Count(buffer.String(), "\n") == 1)
+	assertTrue(t, strings.HasSuffix(buffer.String(), "| bar\n"))
+	assertTrue(t, logger.Sampler.Dropped(LOG_DEBUG) == 3)
+}
+
+func TestSamplerRateCloseToOne(t *testing.T) {
+	sampler := NewSampler(map[Level]float64{LOG_DEBUG: math.Nextafter(1, 0)})
+
+	assertTrue(t, sampler.thresholds[LOG_DEBUG] > math.MaxUint64-4096)
+	assertTrue(t, sampler.Keep(LOG_DEBUG, SequentialTraceId(1)))
+}
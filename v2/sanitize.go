@@ -0,0 +1,88 @@
+package sigolo
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Sanitization determines how control characters within messages and fields are handled.
+type Sanitization int
+
+const (
+	// SANITIZE_NONE prints messages as they are.
+	SANITIZE_NONE Sanitization = iota
+	// SANITIZE_ESCAPE escapes line breaks and other control characters (like ANSI escape sequences), so that every entry
+	// is exactly one line and user input can't forge entries or change the terminal. Backslashes are escaped as well, so
+	// that e.g. "\n" in the output is always an escaped line break.
+	SANITIZE_ESCAPE
+	// SANITIZE_MULTILINE is like SANITIZE_ESCAPE but keeps line breaks. Each continuation line starts with the
+	// MultilineMarker, so it can't be mistaken for a new entry. This is useful for e.g. the output of Stack(...).
+	SANITIZE_MULTILINE
+)
+
+// MultilineMarker is put in front of each continuation line when SANITIZE_MULTILINE is used.
+var MultilineMarker = "    > "
+
+// sanitize returns the text with control characters handled according to the given mode.
+func sanitize(text string, mode Sanitization) string {
+	if mode == SANITIZE_NONE || (!needsSanitization(text) && strings.IndexByte(text, '\\') == -1) {
+		return text
+	}
+
+	builder := strings.Builder{}
+	builder.Grow(len(text) + 16)
+
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+
+		switch {
+		case mode == SANITIZE_MULTILINE && r == '\r' && strings.HasPrefix(text[i+1:], "\n"):
+			// Treat CRLF as one line break
+		case mode == SANITIZE_MULTILINE && r == '\n':
+			builder.WriteByte('\n')
+			builder.WriteString(MultilineMarker)
+		case r == '\\':
+			builder.WriteString(`\\`)
+		case r == utf8.RuneError && size == 1:
+			builder.WriteString(`\x`)
+			builder.WriteString(strconv.FormatUint(uint64(text[i]), 16))
+		case isControlCharacter(r):
+			// Results in e.g. \n or \x1b
+			quoted := strconv.QuoteRuneToASCII(r)
+			builder.WriteString(quoted[1 : len(quoted)-1])
+		default:
+			builder.WriteString(text[i : i+size])
+		}
+
+		i += size
+	}
+
+	return builder.String()
+}
+
+// needsSanitization checks if there's anything to do, so that normal messages are not copied.
+func needsSanitization(text string) bool {
+	for i := 0; i < len(text); {
+		if text[i] < utf8.RuneSelf {
+			if isControlCharacter(rune(text[i])) {
+				return true
+			}
+			i++
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if (r == utf8.RuneError && size == 1) || isControlCharacter(r) {
+			return true
+		}
+		i += size
+	}
+	return false
+}
+
+// isControlCharacter returns true for C0 (except tabs) and C1 control characters as well as the Unicode line and
+// paragraph separators, which some viewers treat as line breaks.
+func isControlCharacter(r rune) bool {
+	return (r < 0x20 && r != '\t') || (r >= 0x7f && r <= 0x9f) || r == '\u2028' || r == '\u2029'
+}
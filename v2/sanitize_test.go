@@ -0,0 +1,76 @@
+package sigolo
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSanitizeEscape(t *testing.T) {
+	tests := map[string]string{
+		"foo bar":                       "foo bar",
+		"tab\tstays":                    "tab\tstays",
+		"a\nb\r\nc":                     `a\nb\r\nc`,
+		"\x1b[31mred\x1b[0m":            `\x1b[31mred\x1b[0m`,
+		"äöü → ok":                      "äöü → ok",
+		"c1 \u009b and \u2028 and \xff": `c1 \u009b and \u2028 and \xff`,
+		`backslash \n`:                  `backslash \\n`,
+	}
+
+	for text, expected := range tests {
+		sanitized := sanitize(text, SANITIZE_ESCAPE)
+		if sanitized != expected {
+			t.Errorf("Expected %q but got %q", expected, sanitized)
+		}
+	}
+}
+
+func TestSanitizeMultiline(t *testing.T) {
+	sanitized := sanitize("first\nsecond\r\nthird\x1b[0m", SANITIZE_MULTILINE)
+
+	assertTrue(t, sanitized == "first\n"+MultilineMarker+"second\n"+MultilineMarker+`third\x1b[0m`)
+}
+
+func TestLoggerSanitizesForgedEntries(t *testing.T) {
+	logger, buffer := newBufferLogger(LOG_INFO)
+	logger.Sanitization = SANITIZE_ESCAPE
+	logger.AddHook(FieldHook("user", "foo\nbar"))
+
+	logger.Infof("Hello %s", "x\n2024-01-01 00:00:00.000 [ERROR] forged")
+
+	assertTrue(t, strings.Count(buffer.String(), "\n") == 1)
+	assertTrue(t, strings.HasSuffix(buffer.String(), `| Hello x\n2024-01-01 00:00:00.000 [ERROR] forged user="foo\nbar"`+"\n"))
+}
+
+func TestQuotedFieldsAreNotSanitizedTwice(t *testing.T) {
+	logger, buffer := newBufferLogger(LOG_INFO)
+	logger.Sanitization = SANITIZE_ESCAPE
+	logger.AddHook(FieldHook("quoted", "a\nb"))
+	logger.AddHook(FieldHook("path", `C:\dir`))
+
+	logger.Info("foo")
+
+	assertTrue(t, strings.HasSuffix(buffer.String(), `| foo quoted="a\nb" path=C:\\dir`+"\n"))
+}
+
+func TestLoggerSanitizesMultilineStack(t *testing.T) {
+	logger, buffer := newBufferLogger(LOG_INFO)
+	logger.Sanitization = SANITIZE_MULTILINE
+
+	logger.Stack(errors.New("first\nsecond"))
+
+	lines := strings.Split(strings.TrimSuffix(buffer.String(), "\n"), "\n")
+	assertTrue(t, len(lines) == 2)
+	assertTrue(t, strings.HasSuffix(lines[0], "| first"))
+	assertTrue(t, lines[1] == MultilineMarker+"second")
+}
+
+func TestJsonIsNotSanitizedTwice(t *testing.T) {
+	logger, buffer := newBufferLogger(LOG_INFO)
+	logger.Sanitization = SANITIZE_ESCAPE
+	logger.FormatFunctions[LOG_INFO] = LogJson
+
+	logger.Info("a\nb \\ c")
+
+	assertTrue(t, strings.HasSuffix(buffer.String(), `"message":"a\nb \\ c"}`+"\n"))
+}
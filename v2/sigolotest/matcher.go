@@ -0,0 +1,110 @@
+package sigolotest
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/hauke96/sigolo/v2"
+)
+
+// Matcher checks a single property of an entry.
+type Matcher struct {
+	// Description is used in failure messages, e.g. "containing "foo"".
+	Description string
+	Match       func(entry *sigolo.Entry) bool
+}
+
+// Level matches entries of the given level.
+func Level(level sigolo.Level) Matcher {
+	return Matcher{
+		Description: "with level " + level.String(),
+		Match: func(entry *sigolo.Entry) bool {
+			return entry.Level == level
+		},
+	}
+}
+
+// Containing matches entries whose message contains the given text.
+func Containing(text string) Matcher {
+	return Matcher{
+		Description: fmt.Sprintf("containing %q", text),
+		Match: func(entry *sigolo.Entry) bool {
+			return strings.Contains(entry.Message, text)
+		},
+	}
+}
+
+// Matching matches entries whose message matches the given regular expression.
+func Matching(pattern *regexp.Regexp) Matcher {
+	return Matcher{
+		Description: fmt.Sprintf("matching %q", pattern.String()),
+		Match: func(entry *sigolo.Entry) bool {
+			return pattern.MatchString(entry.Message)
+		},
+	}
+}
+
+// FromFile matches entries logged from the given file. The file is compared to the caller as shown by the logger, so
+// "bar.go" matches callers like "bar.go:42" and "/src/foo/bar.go:42" and "foo/bar.go" matches the latter one. This
+// doesn't work with the CALLER_FUNCTION format.
+func FromFile(file string) Matcher {
+	return Matcher{
+		Description: "from " + file,
+		Match: func(entry *sigolo.Entry) bool {
+			callerFile := entry.Caller
+			if lineStart := strings.LastIndexByte(callerFile, ':'); lineStart != -1 {
+				callerFile = callerFile[:lineStart]
+			}
+			return callerFile == file || strings.HasSuffix(callerFile, "/"+file)
+		},
+	}
+}
+
+// WithField matches entries having a field with the given key and value. Values are compared by their formatted form, so
+// WithField("count", "3") matches a field with the integer 3.
+func WithField(key string, value interface{}) Matcher {
+	formattedValue := fmt.Sprint(value)
+	return Matcher{
+		Description: fmt.Sprintf("with field %s=%s", key, formattedValue),
+		Match: func(entry *sigolo.Entry) bool {
+			for _, field := range entry.Fields {
+				if field.Key == key && fmt.Sprint(field.Value) == formattedValue {
+					return true
+				}
+			}
+			return false
+		},
+	}
+}
+
+// WithTraceId matches entries of the given trace ID.
+func WithTraceId(traceId sigolo.TraceId) Matcher {
+	return Matcher{
+		Description: "with trace ID " + traceId.String(),
+		Match: func(entry *sigolo.Entry) bool {
+			return entry.TraceId == traceId
+		},
+	}
+}
+
+func matchesAll(entry *sigolo.Entry, matchers []Matcher) bool {
+	for _, matcher := range matchers {
+		if !matcher.Match(entry) {
+			return false
+		}
+	}
+	return true
+}
+
+func describeAll(matchers []Matcher) string {
+	if len(matchers) == 0 {
+		return "at all"
+	}
+
+	descriptions := make([]string, len(matchers))
+	for i, matcher := range matchers {
+		descriptions[i] = matcher.Description
+	}
+	return strings.Join(descriptions, ", ")
+}
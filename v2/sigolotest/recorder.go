@@ -0,0 +1,167 @@
+// Package sigolotest helps testing code that uses sigolo. A Recorder captures the entries of a logger in memory, so that
+// tests can check what has been logged without redirecting the outputs of sigolo.
+package sigolotest
+
+import (
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hauke96/sigolo/v2"
+)
+
+// Recorder keeps all entries logged by its Logger.
+type Recorder struct {
+	// Logger writes its entries into this recorder. Its fields, e.g. the LogLevel, can be changed as needed.
+	Logger *sigolo.Logger
+
+	mutex          sync.Mutex
+	entries        []sigolo.Entry
+	expectedErrors [][]Matcher
+}
+
+// NewRecorder creates a recorder with a logger of the given level. The output of the logger is discarded.
+func NewRecorder(level sigolo.Level) *Recorder {
+	return newRecorder(sigolo.NewLoggerl(level), io.Discard)
+}
+
+// NewTestRecorder is like NewRecorder but the output of the logger is written to the test log, see NewWriter.
+func NewTestRecorder(t testing.TB, level sigolo.Level) *Recorder {
+	return newRecorder(sigolo.NewLoggerl(level), NewWriter(t))
+}
+
+// CaptureDefault records all entries of the DefaultLogger, which includes the package level functions like
+// sigolo.Info(...), and writes its output to the test log. The DefaultLogger is restored when the test finishes.
+//
+// The DefaultLogger is replaced by this, so functions like sigolo.SetDefaultLogLevel(...) must be called before.
+func CaptureDefault(t testing.TB) *Recorder {
+	logger := *sigolo.DefaultLogger
+	recorder := newRecorder(&logger, NewWriter(t))
+
+	sigolo.DefaultLogger = recorder.Logger
+	t.Cleanup(func() {
+		sigolo.DefaultLogger = sigolo.GetLoggerWithCurrentDefaults()
+	})
+
+	return recorder
+}
+
+func newRecorder(logger *sigolo.Logger, output io.Writer) *Recorder {
+	recorder := &Recorder{Logger: logger}
+
+	logger.AddObserver(recorder.record)
+
+	logger.LevelOutputs = map[sigolo.Level]io.Writer{}
+	for _, level := range levels {
+		logger.LevelOutputs[level] = output
+	}
+
+	return recorder
+}
+
+var levels = []sigolo.Level{sigolo.LOG_PLAIN, sigolo.LOG_TRACE, sigolo.LOG_DEBUG, sigolo.LOG_INFO, sigolo.LOG_WARN, sigolo.LOG_ERROR, sigolo.LOG_FATAL}
+
+// record is the observer storing a copy of each written entry, so that fields added by hooks are included and vetoed
+// entries are not.
+func (r *Recorder) record(entry *sigolo.Entry) {
+	recorded := *entry
+	recorded.Fields = append([]sigolo.Field(nil), entry.Fields...)
+
+	r.mutex.Lock()
+	r.entries = append(r.entries, recorded)
+	r.mutex.Unlock()
+}
+
+// Entries returns all entries recorded so far.
+func (r *Recorder) Entries() []sigolo.Entry {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]sigolo.Entry(nil), r.entries...)
+}
+
+// Find returns all entries matching all the given matchers.
+func (r *Recorder) Find(matchers ...Matcher) []sigolo.Entry {
+	var found []sigolo.Entry
+	for _, entry := range r.Entries() {
+		if matchesAll(&entry, matchers) {
+			found = append(found, entry)
+		}
+	}
+	return found
+}
+
+// Reset removes all recorded entries.
+func (r *Recorder) Reset() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.entries = nil
+}
+
+// AssertLogged fails the test when no entry matches all the given matchers, e.g.
+//
+//	recorder.AssertLogged(t, sigolotest.Level(sigolo.LOG_ERROR), sigolotest.Containing("foo"), sigolotest.FromFile("bar.go"))
+func (r *Recorder) AssertLogged(t testing.TB, matchers ...Matcher) {
+	t.Helper()
+	if len(r.Find(matchers...)) == 0 {
+		t.Errorf("Expected an entry %s but there was none. Recorded entries:\n%s", describeAll(matchers), r.describeEntries())
+	}
+}
+
+// AssertNotLogged fails the test when an entry matches all the given matchers.
+func (r *Recorder) AssertNotLogged(t testing.TB, matchers ...Matcher) {
+	t.Helper()
+	for _, entry := range r.Find(matchers...) {
+		t.Errorf("Expected no entry %s but got: %s", describeAll(matchers), describeEntry(&entry))
+	}
+}
+
+// FailOnUnexpectedErrors fails the test when it finishes and ERROR or FATAL entries have been recorded that aren't
+// allowed by ExpectError(...).
+func (r *Recorder) FailOnUnexpectedErrors(t testing.TB) {
+	t.Cleanup(func() {
+		r.mutex.Lock()
+		expectedErrors := r.expectedErrors
+		r.mutex.Unlock()
+
+		for _, entry := range r.Entries() {
+			if entry.Level < sigolo.LOG_ERROR || isExpected(&entry, expectedErrors) {
+				continue
+			}
+			t.Errorf("Unexpected entry: %s", describeEntry(&entry))
+		}
+	})
+}
+
+// ExpectError allows ERROR and FATAL entries matching all the given matchers, see FailOnUnexpectedErrors.
+func (r *Recorder) ExpectError(matchers ...Matcher) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.expectedErrors = append(r.expectedErrors, matchers)
+}
+
+func isExpected(entry *sigolo.Entry, expectedErrors [][]Matcher) bool {
+	for _, matchers := range expectedErrors {
+		if matchesAll(entry, matchers) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Recorder) describeEntries() string {
+	entries := r.Entries()
+	if len(entries) == 0 {
+		return "  (none)"
+	}
+
+	descriptions := make([]string, len(entries))
+	for i := range entries {
+		descriptions[i] = "  " + describeEntry(&entries[i])
+	}
+	return strings.Join(descriptions, "\n")
+}
+
+func describeEntry(entry *sigolo.Entry) string {
+	return "[" + entry.Level.String() + "] " + entry.Caller + " | " + entry.Message
+}
@@ -0,0 +1,140 @@
+package sigolotest
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/hauke96/sigolo/v2"
+)
+
+// fakeTest records failures and logs instead of reporting them.
+type fakeTest struct {
+	testing.TB
+	errors   []string
+	logs     []string
+	outputs  int
+	cleanups []func()
+}
+
+func (f *fakeTest) Helper() {}
+
+func (f *fakeTest) Errorf(format string, args ...interface{}) {
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+func (f *fakeTest) Log(args ...interface{}) {
+	f.logs = append(f.logs, fmt.Sprint(args...))
+}
+
+// Output records the written lines like Log but counts them, see Writer.
+func (f *fakeTest) Output() io.Writer {
+	return writerFunc(func(p []byte) {
+		f.outputs++
+		f.logs = append(f.logs, strings.TrimSuffix(string(p), "\n"))
+	})
+}
+
+type writerFunc func(p []byte)
+
+func (w writerFunc) Write(p []byte) (int, error) {
+	w(p)
+	return len(p), nil
+}
+
+func (f *fakeTest) Cleanup(cleanup func()) {
+	f.cleanups = append(f.cleanups, cleanup)
+}
+
+func (f *fakeTest) finish() {
+	for i := len(f.cleanups) - 1; i >= 0; i-- {
+		f.cleanups[i]()
+	}
+}
+
+func TestRecorderMatchers(t *testing.T) {
+	recorder := NewRecorder(sigolo.LOG_INFO)
+
+	recorder.Logger.Debug("not recorded")
+	recorder.Logger.Infof("Loaded %d items", 3)
+	recorder.Logger.Error("Connection refused")
+
+	if len(recorder.Entries()) != 2 {
+		t.Fatalf("Expected 2 entries but got %d", len(recorder.Entries()))
+	}
+
+	recorder.AssertLogged(t, Level(sigolo.LOG_ERROR), Containing("refused"), FromFile("recorder_test.go"))
+	recorder.AssertLogged(t, Matching(regexp.MustCompile(`^Loaded \d+ items$`)))
+	recorder.AssertNotLogged(t, Containing("not recorded"))
+
+	fake := &fakeTest{}
+	recorder.AssertLogged(fake, Level(sigolo.LOG_WARN))
+	recorder.AssertLogged(fake, Level(sigolo.LOG_ERROR), FromFile("other.go"))
+	recorder.AssertNotLogged(fake, Level(sigolo.LOG_ERROR))
+	if len(fake.errors) != 3 {
+		t.Fatalf("Expected 3 failures but got %d: %q", len(fake.errors), fake.errors)
+	}
+	if !strings.Contains(fake.errors[1], "with level ERROR, from other.go") || !strings.Contains(fake.errors[1], "Connection refused") {
+		t.Errorf("Unexpected failure message: %s", fake.errors[1])
+	}
+
+	recorder.Reset()
+	if len(recorder.Entries()) != 0 {
+		t.Errorf("Expected no entries after reset")
+	}
+}
+
+func TestRecorderFields(t *testing.T) {
+	recorder := NewRecorder(sigolo.LOG_INFO)
+	recorder.Logger.AddHook(sigolo.FieldHook("count", 3))
+	recorder.Logger.AddHook(func(entry *sigolo.Entry) bool {
+		return entry.Message != "vetoed"
+	})
+
+	recorder.Logger.Info("foo")
+	recorder.Logger.Info("vetoed")
+
+	recorder.AssertLogged(t, WithField("count", "3"), WithTraceId(recorder.Logger.LogTraceId))
+	recorder.AssertNotLogged(t, Containing("vetoed"))
+}
+
+func TestFailOnUnexpectedErrors(t *testing.T) {
+	fake := &fakeTest{}
+	recorder := NewTestRecorder(fake, sigolo.LOG_INFO)
+	recorder.FailOnUnexpectedErrors(fake)
+	recorder.ExpectError(Containing("known failure"))
+
+	recorder.Logger.Warn("warning")
+	recorder.Logger.Error("known failure")
+	recorder.Logger.Error("unexpected failure")
+	fake.finish()
+
+	if len(fake.errors) != 1 || !strings.Contains(fake.errors[0], "unexpected failure") {
+		t.Fatalf("Expected one failure for the unexpected entry but got %q", fake.errors)
+	}
+	if len(fake.logs) != 3 || !strings.HasSuffix(fake.logs[0], "| warning") {
+		t.Fatalf("Expected the output in the test log but got %q", fake.logs)
+	}
+
+	recorder.Logger.Info("after the test")
+	if len(fake.logs) != 3 {
+		t.Errorf("Expected no output after the test finished")
+	}
+}
+
+func TestCaptureDefault(t *testing.T) {
+	previous := sigolo.DefaultLogger
+	fake := &fakeTest{}
+	recorder := CaptureDefault(fake)
+
+	sigolo.Info("foo")
+	sigolo.Errorf("bar %d", 42)
+	fake.finish()
+
+	recorder.AssertLogged(t, Level(sigolo.LOG_ERROR), Containing("bar 42"), FromFile("recorder_test.go"))
+	if sigolo.DefaultLogger == recorder.Logger || sigolo.DefaultLogger.LogLevel != previous.LogLevel {
+		t.Errorf("Expected the default logger to be restored")
+	}
+}
@@ -0,0 +1,39 @@
+package sigolotest
+
+import (
+	"sync"
+	"testing"
+)
+
+// Writer writes everything into the log of a test, so that the output is only shown for failed tests or when running
+// "go test -v". Lines written after the test finished are dropped, as the testing package doesn't allow logging anymore.
+//
+// The location t.Log puts in front of each line would be within sigolo, as sigolo can't mark its own functions with
+// t.Helper(). With Go 1.25 and newer, the lines are therefore written via t.Output() without that location, so the
+// caller column of each line is the only and correct location. Older versions fall back to t.Log.
+type Writer struct {
+	t        testing.TB
+	mutex    sync.Mutex
+	finished bool
+}
+
+// NewWriter creates a writer for the given test.
+func NewWriter(t testing.TB) *Writer {
+	writer := &Writer{t: t}
+	t.Cleanup(func() {
+		writer.mutex.Lock()
+		writer.finished = true
+		writer.mutex.Unlock()
+	})
+	return writer
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if !w.finished {
+		logLine(w.t, p)
+	}
+	return len(p), nil
+}
@@ -0,0 +1,13 @@
+//go:build !go1.25
+
+package sigolotest
+
+import (
+	"strings"
+	"testing"
+)
+
+// logLine uses t.Log, as there's no t.Output() before Go 1.25. The location added by t.Log is within sigolo.
+func logLine(t testing.TB, line []byte) {
+	t.Log(strings.TrimSuffix(string(line), "\n"))
+}
@@ -0,0 +1,10 @@
+//go:build go1.25
+
+package sigolotest
+
+import "testing"
+
+// logLine writes the line as it is, the caller column shows where it was logged.
+func logLine(t testing.TB, line []byte) {
+	t.Output().Write(line)
+}
@@ -0,0 +1,15 @@
+//go:build go1.25
+
+package sigolotest
+
+import "testing"
+
+func TestWriterUsesOutputWithoutLocation(t *testing.T) {
+	fake := &fakeTest{}
+
+	NewWriter(fake).Write([]byte("foo\n"))
+
+	if fake.outputs != 1 || len(fake.logs) != 1 || fake.logs[0] != "foo" {
+		t.Errorf("Expected the line to be written via Output but got %d outputs and %q", fake.outputs, fake.logs)
+	}
+}
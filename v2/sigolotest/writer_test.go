@@ -0,0 +1,19 @@
+package sigolotest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hauke96/sigolo/v2"
+)
+
+func TestWriterShowsCallSite(t *testing.T) {
+	fake := &fakeTest{}
+	recorder := NewTestRecorder(fake, sigolo.LOG_INFO)
+
+	recorder.Logger.Info("foo")
+
+	if len(fake.logs) != 1 || !strings.Contains(fake.logs[0], "writer_test.go:") || !strings.HasSuffix(fake.logs[0], "| foo") {
+		t.Errorf("Expected the line with the call site but got %q", fake.logs)
+	}
+}
@@ -0,0 +1,119 @@
+package sigolo
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// Span measures one operation, e.g. loading a file. It logs a line when it starts and when it ends, including the
+// duration and whether the operation succeeded. The embedded logger logs within the span: Its entries have the same
+// trace ID as the logger that created the span but their own span ID, whose parent is the span ID of that logger.
+type Span struct {
+	*Logger
+	Name string
+
+	level    Level
+	start    time.Time
+	boundary *Logger
+	ended    atomic.Bool
+	// threadId is the thread of the span in the ChromeTrace, if there is one.
+	threadId int
+}
+
+// Span starts a span with the INFO level, which must be ended by calling End() or EndWithError(...).
+func (l *Logger) Span(name string) *Span {
+	return l.startSpan(LOG_INFO, 1, name)
+}
+
+// Spanl is equal to Span(...) but logs the start and the successful end with the given level.
+func (l *Logger) Spanl(level Level, name string) *Span {
+	return l.startSpan(level, 1, name)
+}
+
+// StartSpan starts a span of the DefaultLogger, see Logger.Span for details. All entries of the span have the same trace
+// ID.
+func StartSpan(name string) *Span {
+	logger := *DefaultLogger
+	increaseTraceId()
+	return logger.startSpan(LOG_INFO, 1, name)
+}
+
+func (l *Logger) startSpan(level Level, framesBackward int, name string) *Span {
+	child := *l
+	child.LogParentSpanId = l.LogSpanId
+	child.LogSpanId = NewSpanId()
+	child.spanDepth = l.spanDepth + 1
+
+	// The start and end lines belong to the span but are indented like the lines of the parent.
+	boundary := child
+	boundary.spanDepth = l.spanDepth
+
+	span := &Span{
+		Logger:   &child,
+		Name:     name,
+		level:    level,
+		start:    child.now(),
+		boundary: &boundary,
+	}
+
+	if child.ChromeTrace != nil {
+		span.threadId = child.ChromeTrace.beginSpan(span)
+	}
+
+	if boundary.shouldHandle(level) {
+		boundary.logMessage(level, 1+framesBackward, "Span "+name+" started")
+	}
+	return span
+}
+
+// End logs the end of the span and its duration. Only the first call of End or EndWithError logs something.
+func (s *Span) End() {
+	s.end(1, nil)
+}
+
+// EndWithError is equal to End() but logs an ERROR entry containing the error when it's not nil.
+func (s *Span) EndWithError(err error) {
+	s.end(1, err)
+}
+
+// Duration returns the time since the span started.
+func (s *Span) Duration() time.Duration {
+	return s.boundary.now().Sub(s.start)
+}
+
+func (s *Span) end(framesBackward int, err error) {
+	if s.ended.Swap(true) {
+		return
+	}
+
+	end := s.boundary.now()
+	duration := end.Sub(s.start)
+	level := s.level
+	status := "ok"
+	if err != nil {
+		level = LOG_ERROR
+		status = fmt.Sprintf("error: %v", err)
+	}
+
+	if s.ChromeTrace != nil {
+		s.ChromeTrace.endSpan(s, s.threadId, end, status)
+	}
+
+	if s.boundary.shouldHandle(level) {
+		s.boundary.logMessage(level, 1+framesBackward, fmt.Sprintf("Span %s ended after %s: %s", s.Name, duration, status))
+	}
+}
+
+// SetSpanIndent sets the text that's put in front of messages once per nesting level of spans, e.g. "  ". Only the text
+// formats LogDefault and LogDefaultStatic indent messages. An empty string disables the indentation. This only affects
+// loggers and spans created afterwards.
+func (l *Logger) SetSpanIndent(indent string) {
+	l.spanIndent = indent
+}
+
+// SetDefaultSpanIndent sets the indentation of nested spans of the DefaultLogger, see Logger.SetSpanIndent.
+func SetDefaultSpanIndent(indent string) {
+	spanIndent = indent
+	DefaultLogger = GetLoggerWithCurrentDefaults()
+}
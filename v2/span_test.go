@@ -0,0 +1,97 @@
+package sigolo
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSpan(t *testing.T) {
+	clock := NewFakeClock(time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC))
+	logger, buffer := newBufferLogger(LOG_INFO)
+	logger.Clock = clock
+	logger.LogTraceId = SequentialTraceId(0x2a)
+
+	span := logger.Span("load")
+	span.Info("inside")
+	clock.Advance(1500 * time.Millisecond)
+	span.End()
+	span.End()
+
+	lines := strings.Split(strings.TrimSuffix(buffer.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 lines but got %q", buffer.String())
+	}
+	spanId := "#2a/" + span.LogSpanId.String()
+	assertTrue(t, strings.HasSuffix(lines[0], spanId+" | Span load started"))
+	assertTrue(t, strings.HasSuffix(lines[1], spanId+" | inside"))
+	assertTrue(t, strings.HasSuffix(lines[2], spanId+" | Span load ended after 1.5s: ok"))
+	assertTrue(t, strings.Contains(lines[0], "span_test.go"))
+	assertTrue(t, strings.Contains(lines[2], "span_test.go"))
+
+	assertTrue(t, span.LogTraceId == logger.LogTraceId)
+	assertTrue(t, span.LogParentSpanId == logger.LogSpanId)
+}
+
+func TestSpanWithError(t *testing.T) {
+	logger, buffer := newBufferLogger(LOG_WARN)
+
+	span := logger.Span("load")
+	span.EndWithError(errors.New("file not found"))
+
+	assertTrue(t, strings.Count(buffer.String(), "\n") == 1)
+	assertTrue(t, strings.Contains(buffer.String(), "[ERROR]"))
+	assertTrue(t, strings.Contains(buffer.String(), ": error: file not found\n"))
+}
+
+func TestNestedSpansAreIndented(t *testing.T) {
+	logger, buffer := newBufferLogger(LOG_DEBUG)
+	logger.SetSpanIndent("  ")
+
+	outer := logger.Spanl(LOG_DEBUG, "outer")
+	inner := outer.Span("inner")
+	inner.Info("first\nsecond")
+	inner.End()
+	outer.Info("after")
+	outer.End()
+
+	lines := strings.Split(strings.TrimSuffix(buffer.String(), "\n"), "\n")
+	assertTrue(t, strings.HasSuffix(lines[0], "| Span outer started"))
+	assertTrue(t, strings.HasSuffix(lines[1], "|   Span inner started"))
+	assertTrue(t, strings.HasSuffix(lines[2], "|     first"))
+	assertTrue(t, lines[3] == "    second")
+	assertTrue(t, strings.Contains(lines[4], "|   Span inner ended after"))
+	assertTrue(t, strings.HasSuffix(lines[5], "|   after"))
+	assertTrue(t, strings.Contains(lines[6], "| Span outer ended after"))
+	assertTrue(t, strings.Contains(lines[0], "[DEBUG]"))
+
+	assertTrue(t, inner.LogParentSpanId == outer.LogSpanId)
+}
+
+func TestSpanIndentIsOnlyUsedByTextFormats(t *testing.T) {
+	logger, buffer := newBufferLogger(LOG_INFO)
+	logger.SetSpanIndent("  ")
+	logger.FormatFunctions[LOG_WARN] = LogJson
+
+	span := logger.Span("outer")
+	span.Warn("json")
+	span.Info("text")
+
+	assertTrue(t, strings.Contains(buffer.String(), `"message":"json"}`))
+	assertTrue(t, strings.Contains(buffer.String(), "|   text\n"))
+}
+
+func TestStartSpan(t *testing.T) {
+	buffer := prepareBuffer(t, LOG_INFO)
+	traceId := DefaultLogger.LogTraceId
+
+	span := StartSpan("job")
+	span.Info("inside")
+	span.End()
+
+	assertTrue(t, span.LogTraceId == traceId)
+	assertTrue(t, DefaultLogger.LogTraceId != traceId)
+	assertTrue(t, strings.Count(buffer.String(), "log_test.go") == 0)
+	assertTrue(t, strings.Count(buffer.String(), "span_test.go") == 3)
+}
@@ -0,0 +1,545 @@
+package sigolo
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// LoggingDriver wraps a database/sql driver and logs each query, exec and transaction with its arguments, the number of
+// affected rows, the duration and the error, if any. The logger is taken from the context of the statement (see
+// ContextWithLogger) and falls back to Logger.
+//
+// Arguments wrapped in Redacted are passed to the database as they are but logged as RedactionReplacement.
+type LoggingDriver struct {
+	Driver driver.Driver
+	// Logger is used for statements without logger in their context. When nil, the DefaultLogger is used.
+	Logger *Logger
+	// Level of the statement lines, NewLoggingDriver uses DEBUG. Failed statements are logged as ERROR.
+	Level Level
+	// SlowThreshold logs statements taking at least this long as WARN. A value of 0 disables this.
+	SlowThreshold time.Duration
+	// HideArguments logs only the number of arguments instead of their values.
+	HideArguments bool
+}
+
+// NewLoggingDriver wraps the given driver. Statements are logged at DEBUG level.
+func NewLoggingDriver(d driver.Driver) *LoggingDriver {
+	return &LoggingDriver{
+		Driver: d,
+		Level:  LOG_DEBUG,
+	}
+}
+
+// RegisterLoggingDriver registers a logging variant of the given driver with the given name for sql.Open(...). The
+// driver of an already registered name can be obtained by sql.Open(name, "").Driver() without connecting.
+func RegisterLoggingDriver(name string, d driver.Driver) *LoggingDriver {
+	loggingDriver := NewLoggingDriver(d)
+	sql.Register(name, loggingDriver)
+	return loggingDriver
+}
+
+func (d *LoggingDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.Driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlLoggingConn{conn: conn, driver: d}, nil
+}
+
+// OpenConnector forwards to the wrapped driver when it implements driver.DriverContext, so that its connector is used
+// by sql.Open(...). Otherwise, connections are opened with Open(...) like database/sql does.
+func (d *LoggingDriver) OpenConnector(name string) (driver.Connector, error) {
+	driverContext, ok := d.Driver.(driver.DriverContext)
+	if !ok {
+		return &sqlLoggingConnector{driver: d, name: name}, nil
+	}
+	connector, err := driverContext.OpenConnector(name)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlLoggingConnector{driver: d, connector: connector}, nil
+}
+
+// sqlLoggingConnector wraps the connector of the wrapped driver or, when it has none, opens connections by name.
+type sqlLoggingConnector struct {
+	driver    *LoggingDriver
+	connector driver.Connector
+	name      string
+}
+
+func (c *sqlLoggingConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	if c.connector == nil {
+		return c.driver.Open(c.name)
+	}
+	conn, err := c.connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlLoggingConn{conn: conn, driver: c.driver}, nil
+}
+
+func (c *sqlLoggingConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// Close closes the wrapped connector when it needs that, as sql.DB.Close only closes the connector it knows.
+func (c *sqlLoggingConnector) Close() error {
+	if closer, ok := c.connector.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// logStatement logs the statement. The caller is the first function outside of database/sql and this wrapper.
+func (d *LoggingDriver) logStatement(logger *Logger, start time.Time, kind string, query string, args []driver.NamedValue, result string, err error) {
+	duration := logger.now().Sub(start)
+	level := d.Level
+	if d.SlowThreshold > 0 && duration >= d.SlowThreshold && level < LOG_WARN {
+		level = LOG_WARN
+	}
+	if err != nil {
+		level = LOG_ERROR
+	}
+	if !logger.shouldLogSampled(level) {
+		return
+	}
+
+	message := kind
+	if query != "" {
+		message += " " + query
+	}
+	if len(args) > 0 {
+		message += " " + d.formatArguments(args)
+	}
+	switch {
+	case err != nil:
+		message += fmt.Sprintf(" failed after %s: %v", duration, err)
+	case result != "":
+		message += fmt.Sprintf(": %s in %s", result, duration)
+	default:
+		message += fmt.Sprintf(" in %s", duration)
+	}
+	if d.SlowThreshold > 0 && duration >= d.SlowThreshold {
+		message += " (slow)"
+	}
+
+	caller := getCallerDetailsSkipping(1, logger.CallerFormat, "database/sql.", sigoloFunctionPrefix+"(*sqlLogging", sigoloFunctionPrefix+"(*LoggingDriver)")
+	logger.logCaller(level, caller, logger.LogTraceId, message)
+}
+
+// logger returns the logger of the context or the configured one. Otherwise, the DefaultLogger is copied with a new
+// trace ID, so that each statement gets its own trace ID like the package level functions do.
+func (d *LoggingDriver) logger(ctx context.Context) *Logger {
+	if logger := LoggerFromContext(ctx); logger != nil {
+		return logger
+	}
+	if d.Logger != nil {
+		return d.Logger
+	}
+	logger := *DefaultLogger
+	logger.LogTraceId = newTraceId()
+	return &logger
+}
+
+func (d *LoggingDriver) formatArguments(args []driver.NamedValue) string {
+	if d.HideArguments {
+		return fmt.Sprintf("[%d arguments]", len(args))
+	}
+
+	formatted := make([]string, len(args))
+	for i, arg := range args {
+		var value string
+		switch v := arg.Value.(type) {
+		case redactedSqlArgument:
+			value = RedactionReplacement
+		case string:
+			value = fmt.Sprintf("%q", v)
+		case []byte:
+			value = fmt.Sprintf("<%d bytes>", len(v))
+		case nil:
+			value = "NULL"
+		default:
+			value = fmt.Sprint(v)
+		}
+		if arg.Name != "" {
+			value = arg.Name + "=" + value
+		}
+		formatted[i] = value
+	}
+	return "[" + strings.Join(formatted, ", ") + "]"
+}
+
+// redactedSqlArgument marks an argument that was wrapped in Redacted. It's unwrapped before the statement is executed.
+type redactedSqlArgument struct {
+	value driver.Value
+}
+
+// checkNamedValue converts the argument using the given checker, which may be nil, and keeps track of arguments that
+// must not be logged. Other arguments the checker skips are left to database/sql, which then uses the ColumnConverter
+// of the statement or its default conversion.
+func checkNamedValue(checker driver.NamedValueChecker, namedValue *driver.NamedValue) error {
+	value, redacted := namedValue.Value.(Redacted)
+	if !redacted {
+		if checker == nil {
+			return driver.ErrSkip
+		}
+		return checker.CheckNamedValue(namedValue)
+	}
+
+	namedValue.Value = string(value)
+	err := driver.ErrSkip
+	if checker != nil {
+		err = checker.CheckNamedValue(namedValue)
+	}
+	if errors.Is(err, driver.ErrSkip) {
+		namedValue.Value, err = driver.DefaultParameterConverter.ConvertValue(namedValue.Value)
+	}
+	if err == nil {
+		namedValue.Value = redactedSqlArgument{value: namedValue.Value}
+	}
+	return err
+}
+
+// unwrapArguments returns the arguments as they are passed to the wrapped driver.
+func unwrapArguments(args []driver.NamedValue) []driver.NamedValue {
+	var unwrapped []driver.NamedValue
+	for i, arg := range args {
+		if redacted, ok := arg.Value.(redactedSqlArgument); ok {
+			if unwrapped == nil {
+				// Copy the arguments, as they are still needed for logging.
+				unwrapped = append([]driver.NamedValue(nil), args...)
+			}
+			unwrapped[i].Value = redacted.value
+		}
+	}
+	if unwrapped == nil {
+		return args
+	}
+	return unwrapped
+}
+
+func namedValuesToValues(args []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		if arg.Name != "" {
+			return nil, errors.New("sigolo: the wrapped driver doesn't support named arguments")
+		}
+		values[i] = arg.Value
+	}
+	return values, nil
+}
+
+// rowsAffected formats the number of affected rows, if the driver reports them.
+func rowsAffected(result driver.Result) string {
+	count, err := result.RowsAffected()
+	if err != nil {
+		return ""
+	}
+	if count == 1 {
+		return "1 row affected"
+	}
+	return fmt.Sprintf("%d rows affected", count)
+}
+
+// sqlLoggingConn wraps a connection. It implements all optional interfaces and falls back to the basic methods or
+// driver.ErrSkip when the wrapped connection doesn't implement them, like database/sql does.
+type sqlLoggingConn struct {
+	conn   driver.Conn
+	driver *LoggingDriver
+}
+
+func (c *sqlLoggingConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *sqlLoggingConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var stmt driver.Stmt
+	var err error
+	if preparer, ok := c.conn.(driver.ConnPrepareContext); ok {
+		stmt, err = preparer.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return wrapStmt(&sqlLoggingStmt{stmt: stmt, conn: c, query: query}), nil
+}
+
+func (c *sqlLoggingConn) Close() error {
+	return c.conn.Close()
+}
+
+func (c *sqlLoggingConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *sqlLoggingConn) BeginTx(ctx context.Context, options driver.TxOptions) (driver.Tx, error) {
+	logger := c.driver.logger(ctx)
+	start := logger.now()
+
+	tx, err := c.begin(ctx, options)
+
+	c.driver.logStatement(logger, start, "Begin transaction", "", nil, "", err)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlLoggingTx{tx: tx, driver: c.driver, logger: logger}, nil
+}
+
+// begin starts the transaction like database/sql does for connections without BeginTx: Options other than the default
+// ones are rejected and the context is checked before and after starting the transaction.
+func (c *sqlLoggingConn) begin(ctx context.Context, options driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := c.conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, options)
+	}
+
+	if options.Isolation != driver.IsolationLevel(sql.LevelDefault) {
+		return nil, errors.New("sql: driver does not support non-default isolation level")
+	}
+	if options.ReadOnly {
+		return nil, errors.New("sql: driver does not support read-only transactions")
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	tx, err := c.conn.Begin()
+	if err == nil && ctx.Err() != nil {
+		tx.Rollback()
+		return nil, ctx.Err()
+	}
+	return tx, err
+}
+
+func (c *sqlLoggingConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, hasExecer := c.conn.(driver.ExecerContext)
+	legacyExecer, hasLegacyExecer := c.conn.(driver.Execer)
+	if !hasExecer && !hasLegacyExecer {
+		// database/sql prepares a statement instead, which is logged by sqlLoggingStmt.
+		return nil, driver.ErrSkip
+	}
+
+	logger := c.driver.logger(ctx)
+	start := logger.now()
+	var result driver.Result
+	var err error
+	if hasExecer {
+		result, err = execer.ExecContext(ctx, query, unwrapArguments(args))
+	} else {
+		var values []driver.Value
+		values, err = namedValuesToValues(unwrapArguments(args))
+		if err == nil {
+			err = ctx.Err()
+		}
+		if err == nil {
+			result, err = legacyExecer.Exec(query, values)
+		}
+	}
+	if errors.Is(err, driver.ErrSkip) {
+		return nil, err
+	}
+
+	affected := ""
+	if err == nil {
+		affected = rowsAffected(result)
+	}
+	c.driver.logStatement(logger, start, "Exec", query, args, affected, err)
+	return result, err
+}
+
+func (c *sqlLoggingConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, hasQueryer := c.conn.(driver.QueryerContext)
+	legacyQueryer, hasLegacyQueryer := c.conn.(driver.Queryer)
+	if !hasQueryer && !hasLegacyQueryer {
+		return nil, driver.ErrSkip
+	}
+
+	logger := c.driver.logger(ctx)
+	start := logger.now()
+	var rows driver.Rows
+	var err error
+	if hasQueryer {
+		rows, err = queryer.QueryContext(ctx, query, unwrapArguments(args))
+	} else {
+		var values []driver.Value
+		values, err = namedValuesToValues(unwrapArguments(args))
+		if err == nil {
+			err = ctx.Err()
+		}
+		if err == nil {
+			rows, err = legacyQueryer.Query(query, values)
+		}
+	}
+	if errors.Is(err, driver.ErrSkip) {
+		return nil, err
+	}
+
+	c.driver.logStatement(logger, start, "Query", query, args, "", err)
+	return rows, err
+}
+
+// CheckNamedValue is needed to recognize Redacted arguments before database/sql converts them. All other arguments are
+// skipped, unless the wrapped connection checks them, so that the ColumnConverter of a statement still works.
+func (c *sqlLoggingConn) CheckNamedValue(namedValue *driver.NamedValue) error {
+	checker, _ := c.conn.(driver.NamedValueChecker)
+	return checkNamedValue(checker, namedValue)
+}
+
+func (c *sqlLoggingConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *sqlLoggingConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *sqlLoggingConn) IsValid() bool {
+	if validator, ok := c.conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+// sqlLoggingStmt wraps a statement. The NamedValueChecker and ColumnConverter interfaces are only implemented by the
+// wrappers returned by wrapStmt when the wrapped statement implements them, as database/sql behaves differently
+// depending on them.
+type sqlLoggingStmt struct {
+	stmt  driver.Stmt
+	conn  *sqlLoggingConn
+	query string
+}
+
+type sqlLoggingCheckerStmt struct {
+	*sqlLoggingStmt
+}
+
+type sqlLoggingConverterStmt struct {
+	*sqlLoggingStmt
+}
+
+type sqlLoggingCheckerConverterStmt struct {
+	*sqlLoggingStmt
+}
+
+func wrapStmt(s *sqlLoggingStmt) driver.Stmt {
+	_, isChecker := s.stmt.(driver.NamedValueChecker)
+	_, isConverter := s.stmt.(driver.ColumnConverter)
+	switch {
+	case isChecker && isConverter:
+		return sqlLoggingCheckerConverterStmt{s}
+	case isChecker:
+		return sqlLoggingCheckerStmt{s}
+	case isConverter:
+		return sqlLoggingConverterStmt{s}
+	}
+	return s
+}
+
+func (s *sqlLoggingStmt) Close() error {
+	return s.stmt.Close()
+}
+
+func (s *sqlLoggingStmt) NumInput() int {
+	return s.stmt.NumInput()
+}
+
+func (s *sqlLoggingStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return nil, errors.New("sigolo: Stmt.Exec is not supported, database/sql uses ExecContext")
+}
+
+func (s *sqlLoggingStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return nil, errors.New("sigolo: Stmt.Query is not supported, database/sql uses QueryContext")
+}
+
+func (s *sqlLoggingStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	logger := s.conn.driver.logger(ctx)
+	start := logger.now()
+
+	var result driver.Result
+	var err error
+	if execer, ok := s.stmt.(driver.StmtExecContext); ok {
+		result, err = execer.ExecContext(ctx, unwrapArguments(args))
+	} else {
+		var values []driver.Value
+		values, err = namedValuesToValues(unwrapArguments(args))
+		if err == nil {
+			result, err = s.stmt.Exec(values)
+		}
+	}
+
+	affected := ""
+	if err == nil {
+		affected = rowsAffected(result)
+	}
+	s.conn.driver.logStatement(logger, start, "Exec", s.query, args, affected, err)
+	return result, err
+}
+
+func (s *sqlLoggingStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	logger := s.conn.driver.logger(ctx)
+	start := logger.now()
+
+	var rows driver.Rows
+	var err error
+	if queryer, ok := s.stmt.(driver.StmtQueryContext); ok {
+		rows, err = queryer.QueryContext(ctx, unwrapArguments(args))
+	} else {
+		var values []driver.Value
+		values, err = namedValuesToValues(unwrapArguments(args))
+		if err == nil {
+			rows, err = s.stmt.Query(values)
+		}
+	}
+
+	s.conn.driver.logStatement(logger, start, "Query", s.query, args, "", err)
+	return rows, err
+}
+
+func (s sqlLoggingCheckerStmt) CheckNamedValue(namedValue *driver.NamedValue) error {
+	return checkNamedValue(s.stmt.(driver.NamedValueChecker), namedValue)
+}
+
+func (s sqlLoggingConverterStmt) ColumnConverter(index int) driver.ValueConverter {
+	return s.stmt.(driver.ColumnConverter).ColumnConverter(index)
+}
+
+func (s sqlLoggingCheckerConverterStmt) CheckNamedValue(namedValue *driver.NamedValue) error {
+	return checkNamedValue(s.stmt.(driver.NamedValueChecker), namedValue)
+}
+
+func (s sqlLoggingCheckerConverterStmt) ColumnConverter(index int) driver.ValueConverter {
+	return s.stmt.(driver.ColumnConverter).ColumnConverter(index)
+}
+
+type sqlLoggingTx struct {
+	tx     driver.Tx
+	driver *LoggingDriver
+	logger *Logger
+}
+
+func (t *sqlLoggingTx) Commit() error {
+	start := t.logger.now()
+	err := t.tx.Commit()
+	t.driver.logStatement(t.logger, start, "Commit transaction", "", nil, "", err)
+	return err
+}
+
+func (t *sqlLoggingTx) Rollback() error {
+	start := t.logger.now()
+	err := t.tx.Rollback()
+	t.driver.logStatement(t.logger, start, "Rollback transaction", "", nil, "", err)
+	return err
+}
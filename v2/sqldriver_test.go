@@ -0,0 +1,370 @@
+package sigolo
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSqlDriver is a minimal driver. Statements containing "fail" return an error, statements containing "slow"
+// advance the clock by one second. Exec reports the number of arguments as affected rows.
+type fakeSqlDriver struct {
+	clock *FakeClock
+}
+
+func (d *fakeSqlDriver) Open(name string) (driver.Conn, error) {
+	conn := &fakeSqlConn{driver: d}
+	switch name {
+	case "direct":
+		return &fakeSqlDirectConn{conn}, nil
+	case "legacy":
+		return &fakeSqlLegacyConn{conn}, nil
+	case "converter":
+		return &fakeSqlConverterConn{conn}, nil
+	}
+	return conn, nil
+}
+
+func (d *fakeSqlDriver) run(query string) error {
+	if strings.Contains(query, "slow") {
+		d.clock.Advance(time.Second)
+	}
+	if strings.Contains(query, "fail") {
+		return errors.New("syntax error")
+	}
+	return nil
+}
+
+// fakeSqlConn only implements the required methods, so database/sql prepares every statement.
+type fakeSqlConn struct {
+	driver *fakeSqlDriver
+}
+
+func (c *fakeSqlConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeSqlStmt{conn: c, query: query}, nil
+}
+
+func (c *fakeSqlConn) Close() error {
+	return nil
+}
+
+func (c *fakeSqlConn) Begin() (driver.Tx, error) {
+	return c, nil
+}
+
+func (c *fakeSqlConn) Commit() error {
+	return nil
+}
+
+func (c *fakeSqlConn) Rollback() error {
+	return nil
+}
+
+// fakeSqlDirectConn executes statements without preparing them.
+type fakeSqlDirectConn struct {
+	*fakeSqlConn
+}
+
+func (c *fakeSqlDirectConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if err := c.driver.run(query); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(len(args)), nil
+}
+
+func (c *fakeSqlDirectConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if err := c.driver.run(query); err != nil {
+		return nil, err
+	}
+	return &fakeSqlRows{}, nil
+}
+
+// fakeSqlLegacyConn executes statements using the deprecated Execer and Queryer interfaces and can't prepare them.
+type fakeSqlLegacyConn struct {
+	*fakeSqlConn
+}
+
+func (c *fakeSqlLegacyConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+
+func (c *fakeSqlLegacyConn) Exec(query string, args []driver.Value) (driver.Result, error) {
+	if err := c.driver.run(query); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(len(args)), nil
+}
+
+func (c *fakeSqlLegacyConn) Query(query string, args []driver.Value) (driver.Rows, error) {
+	if err := c.driver.run(query); err != nil {
+		return nil, err
+	}
+	return &fakeSqlRows{}, nil
+}
+
+// fakeSqlConverterConn prepares statements converting all arguments to strings.
+type fakeSqlConverterConn struct {
+	*fakeSqlConn
+}
+
+func (c *fakeSqlConverterConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeSqlConverterStmt{&fakeSqlStmt{conn: c.fakeSqlConn, query: query}}, nil
+}
+
+type fakeSqlConverterStmt struct {
+	*fakeSqlStmt
+}
+
+func (s *fakeSqlConverterStmt) NumInput() int {
+	return 1
+}
+
+func (s *fakeSqlConverterStmt) ColumnConverter(index int) driver.ValueConverter {
+	return driver.String
+}
+
+type fakeSqlStmt struct {
+	conn  *fakeSqlConn
+	query string
+}
+
+func (s *fakeSqlStmt) Close() error {
+	return nil
+}
+
+func (s *fakeSqlStmt) NumInput() int {
+	return -1
+}
+
+func (s *fakeSqlStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if err := s.conn.driver.run(s.query); err != nil {
+		return nil, err
+	}
+	for _, arg := range args {
+		if _, ok := arg.(redactedSqlArgument); ok {
+			return nil, errors.New("got wrapped argument")
+		}
+	}
+	return driver.RowsAffected(len(args)), nil
+}
+
+func (s *fakeSqlStmt) Query(args []driver.Value) (driver.Rows, error) {
+	if err := s.conn.driver.run(s.query); err != nil {
+		return nil, err
+	}
+	return &fakeSqlRows{}, nil
+}
+
+// fakeSqlRows contains a single row with the value 1.
+type fakeSqlRows struct {
+	done bool
+}
+
+func (r *fakeSqlRows) Columns() []string {
+	return []string{"value"}
+}
+
+func (r *fakeSqlRows) Close() error {
+	return nil
+}
+
+func (r *fakeSqlRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = int64(1)
+	return nil
+}
+
+var (
+	fakeSqlClock          = NewFakeClock(time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC))
+	fakeLoggingDriver     *LoggingDriver
+	registerFakeSqlDriver sync.Once
+)
+
+func openFakeDatabase(t *testing.T, dataSource string) (*sql.DB, *Logger, *strings.Builder) {
+	registerFakeSqlDriver.Do(func() {
+		fakeLoggingDriver = RegisterLoggingDriver("sigolo-fake", &fakeSqlDriver{clock: fakeSqlClock})
+		fakeLoggingDriver.SlowThreshold = 500 * time.Millisecond
+	})
+
+	logger, _ := newBufferLogger(LOG_DEBUG)
+	output := &strings.Builder{}
+	for level := range logger.LevelOutputs {
+		logger.LevelOutputs[level] = output
+	}
+	logger.Clock = fakeSqlClock
+	fakeLoggingDriver.Logger = logger
+
+	db, err := sql.Open("sigolo-fake", dataSource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+	})
+	return db, logger, output
+}
+
+func TestLoggingDriverPreparedStatements(t *testing.T) {
+	db, _, output := openFakeDatabase(t, "")
+
+	_, err := db.Exec("INSERT INTO users VALUES (?, ?, ?)", 42, "foo bar", Redacted("secret"))
+	assertTrue(t, err == nil)
+	row := db.QueryRow("SELECT slow FROM users WHERE id = ?", 42)
+	var value int
+	assertTrue(t, row.Scan(&value) == nil && value == 1)
+	_, err = db.Exec("fail")
+	assertTrue(t, err != nil)
+
+	lines := strings.Split(strings.TrimSuffix(output.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 lines but got %q", output.String())
+	}
+	assertTrue(t, strings.Contains(lines[0], "[DEBUG]"))
+	assertTrue(t, strings.Contains(lines[0], "sqldriver_test.go"))
+	assertTrue(t, strings.HasSuffix(lines[0], `| Exec INSERT INTO users VALUES (?, ?, ?) [42, "foo bar", `+RedactionReplacement+`]: 3 rows affected in 0s`))
+	assertTrue(t, strings.Contains(lines[1], "[WARN]"))
+	assertTrue(t, strings.HasSuffix(lines[1], "| Query SELECT slow FROM users WHERE id = ? [42] in 1s (slow)"))
+	assertTrue(t, strings.Contains(lines[2], "[ERROR]"))
+	assertTrue(t, strings.HasSuffix(lines[2], "| Exec fail failed after 0s: syntax error"))
+	assertFalse(t, strings.Contains(output.String(), "secret"))
+}
+
+func TestLoggingDriverDirectStatementsAndTransactions(t *testing.T) {
+	db, _, output := openFakeDatabase(t, "direct")
+
+	tx, err := db.Begin()
+	assertTrue(t, err == nil)
+	_, err = tx.Exec("DELETE FROM users WHERE id = ?", sql.Named("id", 42))
+	assertTrue(t, err == nil)
+	assertTrue(t, tx.Commit() == nil)
+
+	lines := strings.Split(strings.TrimSuffix(output.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 lines but got %q", output.String())
+	}
+	assertTrue(t, strings.HasSuffix(lines[0], "| Begin transaction in 0s"))
+	assertTrue(t, strings.HasSuffix(lines[1], "| Exec DELETE FROM users WHERE id = ? [id=42]: 1 row affected in 0s"))
+	assertTrue(t, strings.HasSuffix(lines[2], "| Commit transaction in 0s"))
+}
+
+func TestLoggingDriverUsesLoggerOfContext(t *testing.T) {
+	db, _, output := openFakeDatabase(t, "direct")
+	logger, buffer := newBufferLogger(LOG_DEBUG)
+	logger.LogTraceId = SequentialTraceId(0x2b)
+	fakeLoggingDriver.HideArguments = true
+	defer func() {
+		fakeLoggingDriver.HideArguments = false
+	}()
+
+	_, err := db.ExecContext(ContextWithLogger(context.Background(), logger), "UPDATE users SET name = ?", "foo")
+
+	assertTrue(t, err == nil)
+	assertTrue(t, output.Len() == 0)
+	assertTrue(t, strings.Contains(buffer.String(), "| #2b | Exec UPDATE users SET name = ? [1 arguments]: 1 row affected in "))
+}
+
+func TestLoggingDriverLegacyConnection(t *testing.T) {
+	db, _, output := openFakeDatabase(t, "legacy")
+
+	_, err := db.Exec("DELETE FROM users WHERE id = ?", 42)
+	assertTrue(t, err == nil)
+	rows, err := db.Query("SELECT value FROM users")
+	assertTrue(t, err == nil)
+	rows.Close()
+
+	lines := strings.Split(strings.TrimSuffix(output.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines but got %q", output.String())
+	}
+	assertTrue(t, strings.HasSuffix(lines[0], "| Exec DELETE FROM users WHERE id = ? [42]: 1 row affected in 0s"))
+	assertTrue(t, strings.HasSuffix(lines[1], "| Query SELECT value FROM users in 0s"))
+}
+
+func TestLoggingDriverUsesColumnConverter(t *testing.T) {
+	db, _, output := openFakeDatabase(t, "converter")
+
+	_, err := db.Exec("DELETE FROM users WHERE id = ?", 42)
+
+	assertTrue(t, err == nil)
+	assertTrue(t, strings.HasSuffix(output.String(), `| Exec DELETE FROM users WHERE id = ? ["42"]: 1 row affected in 0s`+"\n"))
+}
+
+func TestLoggingDriverBeginTx(t *testing.T) {
+	db, _, output := openFakeDatabase(t, "")
+
+	_, err := db.BeginTx(context.Background(), &sql.TxOptions{ReadOnly: true})
+	assertTrue(t, err != nil && strings.Contains(err.Error(), "read-only"))
+	_, err = db.BeginTx(context.Background(), &sql.TxOptions{Isolation: sql.LevelSerializable})
+	assertTrue(t, err != nil && strings.Contains(err.Error(), "isolation level"))
+
+	conn, err := fakeLoggingDriver.Open("")
+	assertTrue(t, err == nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = conn.(driver.ConnBeginTx).BeginTx(ctx, driver.TxOptions{})
+	assertTrue(t, errors.Is(err, context.Canceled))
+
+	assertTrue(t, strings.Count(output.String(), "[ERROR]") == 3)
+}
+
+// fakeSqlConnectorDriver opens connections only via its connector.
+type fakeSqlConnectorDriver struct {
+	fakeSqlDriver
+	connector *fakeSqlConnector
+}
+
+func (d *fakeSqlConnectorDriver) OpenConnector(name string) (driver.Connector, error) {
+	d.connector = &fakeSqlConnector{driver: d, name: name}
+	return d.connector, nil
+}
+
+type fakeSqlConnector struct {
+	driver   *fakeSqlConnectorDriver
+	name     string
+	connects int
+	closed   bool
+}
+
+func (c *fakeSqlConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	c.connects++
+	return c.driver.Open(c.name)
+}
+
+func (c *fakeSqlConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+func (c *fakeSqlConnector) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestLoggingDriverUsesConnectorOfDriver(t *testing.T) {
+	logger, buffer := newBufferLogger(LOG_DEBUG)
+	wrapped := &fakeSqlConnectorDriver{fakeSqlDriver: fakeSqlDriver{clock: fakeSqlClock}}
+	loggingDriver := NewLoggingDriver(wrapped)
+	loggingDriver.Logger = logger
+
+	connector, err := loggingDriver.OpenConnector("direct")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := sql.OpenDB(connector)
+	_, err = db.Exec("DELETE FROM users WHERE id = ?", 42)
+	assertTrue(t, err == nil)
+	assertTrue(t, db.Close() == nil)
+
+	assertTrue(t, connector.Driver() == loggingDriver)
+	assertTrue(t, wrapped.connector.connects == 1)
+	assertTrue(t, wrapped.connector.closed)
+	assertTrue(t, strings.Contains(buffer.String(), "| Exec DELETE FROM users WHERE id = ? [42]: 1 row affected in "))
+}
@@ -0,0 +1,159 @@
+package sigolo
+
+import (
+	"sync"
+	"time"
+)
+
+// throttle decides whether a call site is allowed to log. The state of each call site is shared between all loggers
+// using the same kind of throttle.
+type throttle struct {
+	every    int
+	duration time.Duration
+	once     bool
+}
+
+// throttleState is the state of one call site.
+type throttleState struct {
+	mutex      sync.Mutex
+	calls      int
+	lastLogged time.Time
+	suppressed int
+}
+
+type throttleKey struct {
+	programCounter uintptr
+	throttle       throttle
+}
+
+var (
+	throttleStatesMutex sync.Mutex
+	throttleStates      = map[throttleKey]*throttleState{}
+)
+
+// ThrottledLogger logs through a Logger but only as often as its throttle allows, see Logger.Every(...),
+// Logger.EveryDuration(...) and Logger.Once(). It's a small value, so creating one for each call doesn't allocate.
+type ThrottledLogger struct {
+	logger   *Logger
+	throttle throttle
+}
+
+// Every returns a logger that only logs every n-th call of each call site, starting with the first one. When a call site
+// logs again after some calls were suppressed, a line with the number of suppressed messages is logged before.
+//
+// Example:
+//
+//	logger.Every(100).Warnf("Connection to %s failed", host)
+func (l *Logger) Every(n int) ThrottledLogger {
+	return ThrottledLogger{logger: l, throttle: throttle{every: n}}
+}
+
+// EveryDuration returns a logger that logs at most once per given duration for each call site. When a call site logs
+// again after some calls were suppressed, a line with the number of suppressed messages is logged before.
+func (l *Logger) EveryDuration(duration time.Duration) ThrottledLogger {
+	return ThrottledLogger{logger: l, throttle: throttle{duration: duration}}
+}
+
+// Once returns a logger that logs only the first call of each call site.
+func (l *Logger) Once() ThrottledLogger {
+	return ThrottledLogger{logger: l, throttle: throttle{once: true}}
+}
+
+func (t ThrottledLogger) Trace(message string) {
+	if t.logger.shouldHandle(LOG_TRACE) {
+		t.logger.logThrottled(&t.throttle, LOG_TRACE, 1, message, nil, false)
+	}
+}
+
+func (t ThrottledLogger) Tracef(format string, args ...interface{}) {
+	if t.logger.shouldHandle(LOG_TRACE) {
+		t.logger.logThrottled(&t.throttle, LOG_TRACE, 1, format, args, true)
+	}
+}
+
+func (t ThrottledLogger) Debug(message string) {
+	if t.logger.shouldHandle(LOG_DEBUG) {
+		t.logger.logThrottled(&t.throttle, LOG_DEBUG, 1, message, nil, false)
+	}
+}
+
+func (t ThrottledLogger) Debugf(format string, args ...interface{}) {
+	if t.logger.shouldHandle(LOG_DEBUG) {
+		t.logger.logThrottled(&t.throttle, LOG_DEBUG, 1, format, args, true)
+	}
+}
+
+func (t ThrottledLogger) Info(message string) {
+	if t.logger.shouldHandle(LOG_INFO) {
+		t.logger.logThrottled(&t.throttle, LOG_INFO, 1, message, nil, false)
+	}
+}
+
+func (t ThrottledLogger) Infof(format string, args ...interface{}) {
+	if t.logger.shouldHandle(LOG_INFO) {
+		t.logger.logThrottled(&t.throttle, LOG_INFO, 1, format, args, true)
+	}
+}
+
+func (t ThrottledLogger) Warn(message string) {
+	if t.logger.shouldHandle(LOG_WARN) {
+		t.logger.logThrottled(&t.throttle, LOG_WARN, 1, message, nil, false)
+	}
+}
+
+func (t ThrottledLogger) Warnf(format string, args ...interface{}) {
+	if t.logger.shouldHandle(LOG_WARN) {
+		t.logger.logThrottled(&t.throttle, LOG_WARN, 1, format, args, true)
+	}
+}
+
+func (t ThrottledLogger) Error(message string) {
+	if t.logger.shouldHandle(LOG_ERROR) {
+		t.logger.logThrottled(&t.throttle, LOG_ERROR, 1, message, nil, false)
+	}
+}
+
+func (t ThrottledLogger) Errorf(format string, args ...interface{}) {
+	if t.logger.shouldHandle(LOG_ERROR) {
+		t.logger.logThrottled(&t.throttle, LOG_ERROR, 1, format, args, true)
+	}
+}
+
+// allow returns whether the call site of the given program counter is allowed to log and how many calls were
+// suppressed since the call site logged the last time.
+func (t *throttle) allow(programCounter uintptr, now time.Time) (bool, int) {
+	key := throttleKey{programCounter: programCounter, throttle: *t}
+	throttleStatesMutex.Lock()
+	state, ok := throttleStates[key]
+	if !ok {
+		state = &throttleState{}
+		throttleStates[key] = state
+	}
+	throttleStatesMutex.Unlock()
+
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+
+	allowed := false
+	switch {
+	case t.once:
+		allowed = state.calls == 0
+	case t.duration > 0:
+		allowed = state.calls == 0 || now.Sub(state.lastLogged) >= t.duration
+	case t.every > 1:
+		allowed = state.calls%t.every == 0
+	default:
+		allowed = true
+	}
+	state.calls++
+
+	if !allowed {
+		state.suppressed++
+		return false, 0
+	}
+
+	suppressed := state.suppressed
+	state.suppressed = 0
+	state.lastLogged = now
+	return true, suppressed
+}
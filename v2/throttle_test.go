@@ -0,0 +1,120 @@
+package sigolo
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newThrottleTestLogger returns a logger writing into the buffer and resets the state of all throttled call sites, so
+// that the tests can be run several times.
+func newThrottleTestLogger() (*Logger, *bytes.Buffer) {
+	throttleStatesMutex.Lock()
+	throttleStates = map[throttleKey]*throttleState{}
+	throttleStatesMutex.Unlock()
+	return newBufferLogger(LOG_INFO)
+}
+
+func countLines(output string) int {
+	return strings.Count(output, "\n")
+}
+
+func TestEvery(t *testing.T) {
+	logger, buffer := newThrottleTestLogger()
+
+	for i := 0; i < 7; i++ {
+		logger.Every(3).Warnf("message %d", i)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buffer.String(), "\n"), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("Expected 5 lines but got %d: %q", len(lines), buffer.String())
+	}
+	assertTrue(t, strings.HasSuffix(lines[0], "| message 0"))
+	assertTrue(t, strings.HasSuffix(lines[1], "| Suppressed 2 similar messages"))
+	assertTrue(t, strings.HasSuffix(lines[2], "| message 3"))
+	assertTrue(t, strings.HasSuffix(lines[3], "| Suppressed 2 similar messages"))
+	assertTrue(t, strings.HasSuffix(lines[4], "| message 6"))
+}
+
+func TestEveryDuration(t *testing.T) {
+	logger, buffer := newThrottleTestLogger()
+
+	for i := 0; i < 3; i++ {
+		logger.EveryDuration(time.Hour).Info("foo")
+	}
+
+	assertTrue(t, countLines(buffer.String()) == 1)
+}
+
+func TestOnce(t *testing.T) {
+	logger, buffer := newThrottleTestLogger()
+
+	for i := 0; i < 3; i++ {
+		logger.Once().Info("foo")
+		logger.Once().Info("bar")
+	}
+
+	output := buffer.String()
+	assertTrue(t, countLines(output) == 2)
+	assertTrue(t, strings.Count(output, "| foo\n") == 1)
+	assertTrue(t, strings.Count(output, "| bar\n") == 1)
+}
+
+func TestThrottleDoesNotChangeLogger(t *testing.T) {
+	logger, buffer := newThrottleTestLogger()
+
+	logger.Once().Info("foo")
+	for i := 0; i < 2; i++ {
+		logger.Info("bar")
+	}
+
+	assertTrue(t, countLines(buffer.String()) == 3)
+}
+
+func TestThrottleChecksBeforeFormatting(t *testing.T) {
+	logger, buffer := newThrottleTestLogger()
+	formatted := 0
+	counter := stringerFunc(func() string {
+		formatted++
+		return "foo"
+	})
+
+	for i := 0; i < 3; i++ {
+		logger.Once().Infof("%s", counter)
+	}
+
+	assertTrue(t, countLines(buffer.String()) == 1)
+	assertTrue(t, formatted == 1)
+}
+
+func TestThrottledEntriesAreDumped(t *testing.T) {
+	logger, buffer := newThrottleTestLogger()
+	logger.Recorder = NewFlightRecorder(10)
+
+	for i := 0; i < 2; i++ {
+		logger.Once().Infof("message %d", i)
+	}
+	logger.Error("failure")
+
+	output := buffer.String()
+	assertTrue(t, strings.Count(output, "message 0") == 1)
+	assertTrue(t, strings.Count(output, "message 1") == 1)
+}
+
+func TestThrottleDoesNotAllocate(t *testing.T) {
+	logger, _ := newThrottleTestLogger()
+
+	allocations := testing.AllocsPerRun(100, func() {
+		logger.Once().Info("foo")
+	})
+
+	assertTrue(t, allocations == 0)
+}
+
+type stringerFunc func() string
+
+func (f stringerFunc) String() string {
+	return f()
+}
@@ -0,0 +1,48 @@
+package sigolo
+
+import (
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// formattedTimestamp is a time formatted with a certain format. Entries logged within the same millisecond share the
+// same formatted time, which saves formatting and allocating it again. The same instant has different texts in different
+// locations, so the location is part of the key as well.
+type formattedTimestamp struct {
+	unixMilli int64
+	location  *time.Location
+	format    string
+	text      string
+}
+
+var lastTimestamp atomic.Pointer[formattedTimestamp]
+
+// formatTimestamp returns the time formatted using the given format and caches the result for the current millisecond.
+func formatTimestamp(t time.Time, format string) string {
+	if !isCacheableTimeFormat(format) {
+		return t.Format(format)
+	}
+
+	unixMilli := t.UnixMilli()
+	location := t.Location()
+	cached := lastTimestamp.Load()
+	if cached != nil && cached.unixMilli == unixMilli && cached.location == location && cached.format == format {
+		return cached.text
+	}
+
+	text := t.Format(format)
+	lastTimestamp.Store(&formattedTimestamp{
+		unixMilli: unixMilli,
+		location:  location,
+		format:    format,
+		text:      text,
+	})
+	return text
+}
+
+// isCacheableTimeFormat returns false when the format contains fractional seconds more precise than milliseconds, e.g.
+// ".000000". Those formats produce different texts within the same millisecond.
+func isCacheableTimeFormat(format string) bool {
+	return !strings.Contains(format, "0000") && !strings.Contains(format, "9999")
+}
@@ -0,0 +1,291 @@
+package sigolo
+
+import (
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Header names of the W3C trace context, see https://www.w3.org/TR/trace-context/.
+const (
+	TraceparentHeader = "traceparent"
+	TracestateHeader  = "tracestate"
+)
+
+// SpanId identifies one operation within a trace, e.g. the handling of a request. It's rendered as 16 hex digits.
+type SpanId uint64
+
+// NewSpanId returns a random span ID, which is never 0.
+func NewSpanId() SpanId {
+	for {
+		if spanId := SpanId(rand.Uint64()); spanId != 0 {
+			return spanId
+		}
+	}
+}
+
+// Append appends the span ID as 16 hex digits to the buffer.
+func (s SpanId) Append(buffer []byte) []byte {
+	return appendHex(buffer, uint64(s))
+}
+
+func (s SpanId) String() string {
+	var buffer [16]byte
+	return string(s.Append(buffer[:0]))
+}
+
+// MarshalText renders the span ID like String does.
+func (s SpanId) MarshalText() ([]byte, error) {
+	return s.Append(nil), nil
+}
+
+// TraceFlags are the flags of the W3C trace context.
+type TraceFlags byte
+
+// TRACE_FLAG_SAMPLED means that the caller may have recorded the trace.
+const TRACE_FLAG_SAMPLED TraceFlags = 0x01
+
+// TraceStateMember is one key value pair of the tracestate header.
+type TraceStateMember struct {
+	Key   string
+	Value string
+}
+
+// TraceState contains vendor specific information of the W3C trace context. It's passed on unchanged.
+type TraceState []TraceStateMember
+
+const maxTraceStateMembers = 32
+
+// ParseTraceState parses the value of a tracestate header. Empty members are ignored.
+func ParseTraceState(header string) (TraceState, error) {
+	var state TraceState
+	for _, member := range strings.Split(header, ",") {
+		member = strings.Trim(member, " \t")
+		if member == "" {
+			continue
+		}
+
+		key, value, found := strings.Cut(member, "=")
+		if !found || !isValidTraceStateKey(key) || !isValidTraceStateValue(value) {
+			return nil, errors.New("invalid tracestate member " + strconv.Quote(member))
+		}
+		if state.Get(key) != "" {
+			return nil, errors.New("duplicate tracestate key " + strconv.Quote(key))
+		}
+		state = append(state, TraceStateMember{Key: key, Value: value})
+	}
+
+	if len(state) > maxTraceStateMembers {
+		return nil, errors.New("tracestate has more than " + strconv.Itoa(maxTraceStateMembers) + " members")
+	}
+	return state, nil
+}
+
+// isValidTraceStateKey checks for keys like "foo" or "tenant@vendor" made of lower case letters, digits and "_-*/".
+func isValidTraceStateKey(key string) bool {
+	tenant, vendor, multiTenant := strings.Cut(key, "@")
+	if !multiTenant {
+		return len(key) <= 256 && isTraceStateKeyPart(key, true)
+	}
+	return len(tenant) <= 241 && len(vendor) <= 14 && isTraceStateKeyPart(tenant, false) && isTraceStateKeyPart(vendor, true)
+}
+
+func isTraceStateKeyPart(part string, mustStartWithLetter bool) bool {
+	if part == "" || (mustStartWithLetter && (part[0] < 'a' || part[0] > 'z')) {
+		return false
+	}
+	for i := 0; i < len(part); i++ {
+		c := part[i]
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '_' && c != '-' && c != '*' && c != '/' {
+			return false
+		}
+	}
+	return true
+}
+
+// isValidTraceStateValue checks for printable ASCII characters except "," and "=" without trailing space.
+func isValidTraceStateValue(value string) bool {
+	if value == "" || len(value) > 256 || value[len(value)-1] == ' ' {
+		return false
+	}
+	for i := 0; i < len(value); i++ {
+		if value[i] < 0x20 || value[i] > 0x7e || value[i] == ',' || value[i] == '=' {
+			return false
+		}
+	}
+	return true
+}
+
+// Get returns the value of the given key or "" if there's no such key.
+func (s TraceState) Get(key string) string {
+	for _, member := range s {
+		if member.Key == key {
+			return member.Value
+		}
+	}
+	return ""
+}
+
+// String renders the trace state as value of a tracestate header.
+func (s TraceState) String() string {
+	builder := strings.Builder{}
+	for i, member := range s {
+		if i > 0 {
+			builder.WriteByte(',')
+		}
+		builder.WriteString(member.Key)
+		builder.WriteByte('=')
+		builder.WriteString(member.Value)
+	}
+	return builder.String()
+}
+
+// TraceContext is the information of the W3C traceparent and tracestate headers.
+type TraceContext struct {
+	TraceId TraceId
+	// ParentId is the span of the caller.
+	ParentId SpanId
+	Flags    TraceFlags
+	State    TraceState
+}
+
+// ParseTraceparent parses the value of a traceparent header like "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01".
+// The trace state is left empty.
+func ParseTraceparent(header string) (TraceContext, error) {
+	invalid := errors.New("invalid traceparent " + strconv.Quote(header))
+
+	// Later versions may append further fields, which are ignored.
+	if len(header) < 55 || (len(header) > 55 && header[55] != '-') || header[2] != '-' || header[35] != '-' || header[52] != '-' {
+		return TraceContext{}, invalid
+	}
+
+	version, ok := parseLowerHex(header[0:2])
+	if !ok || version == 0xff || (version == 0 && len(header) != 55) {
+		return TraceContext{}, invalid
+	}
+	high, highOk := parseLowerHex(header[3:19])
+	low, lowOk := parseLowerHex(header[19:35])
+	parentId, parentOk := parseLowerHex(header[36:52])
+	flags, flagsOk := parseLowerHex(header[53:55])
+	if !highOk || !lowOk || !parentOk || !flagsOk || (high == 0 && low == 0) || parentId == 0 {
+		return TraceContext{}, invalid
+	}
+
+	return TraceContext{
+		TraceId:  TraceId{High: high, Low: low, Format: TRACE_ID_HEX128},
+		ParentId: SpanId(parentId),
+		Flags:    TraceFlags(flags),
+	}, nil
+}
+
+// parseLowerHex parses up to 16 lower case hex digits. Upper case digits are invalid in the W3C trace context.
+func parseLowerHex(text string) (uint64, bool) {
+	var value uint64
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case c >= '0' && c <= '9':
+			value = value<<4 | uint64(c-'0')
+		case c >= 'a' && c <= 'f':
+			value = value<<4 | uint64(c-'a'+10)
+		default:
+			return 0, false
+		}
+	}
+	return value, true
+}
+
+// Traceparent renders the value of a traceparent header. The trace ID is always rendered with 32 hex digits, so
+// sequential and 64 bit trace IDs are padded with zeros.
+func (c TraceContext) Traceparent() string {
+	buffer := make([]byte, 0, 55)
+	buffer = append(buffer, "00-"...)
+	buffer = TraceId{High: c.TraceId.High, Low: c.TraceId.Low, Format: TRACE_ID_HEX128}.Append(buffer)
+	buffer = append(buffer, '-')
+	buffer = c.ParentId.Append(buffer)
+	buffer = append(buffer, '-')
+	buffer = append(buffer, "0123456789abcdef"[c.Flags>>4], "0123456789abcdef"[c.Flags&0xf])
+	return string(buffer)
+}
+
+// TraceContextFromRequest returns the trace context of the request headers. It returns false when there's no valid
+// traceparent header. An invalid tracestate header is ignored.
+func TraceContextFromRequest(r *http.Request) (TraceContext, bool) {
+	traceparents := r.Header.Values(TraceparentHeader)
+	if len(traceparents) != 1 {
+		return TraceContext{}, false
+	}
+
+	context, err := ParseTraceparent(traceparents[0])
+	if err != nil {
+		return TraceContext{}, false
+	}
+
+	// Several tracestate headers are combined as if they were a single one.
+	state, err := ParseTraceState(strings.Join(r.Header.Values(TracestateHeader), ","))
+	if err == nil {
+		context.State = state
+	}
+	return context, true
+}
+
+// NewLoggerFromRequest creates a logger with the current defaults for handling the request. When the request has a
+// valid traceparent header, the trace ID is taken from it and the span of the caller becomes the parent span.
+// Otherwise, a new trace ID is used. In both cases the logger gets a new span ID.
+func NewLoggerFromRequest(r *http.Request) *Logger {
+	logger := GetLoggerWithCurrentDefaults()
+	logger.LogSpanId = NewSpanId()
+
+	if context, ok := TraceContextFromRequest(r); ok {
+		logger.LogTraceId = context.TraceId
+		logger.LogParentSpanId = context.ParentId
+		logger.TraceFlags = context.Flags
+		logger.TraceState = context.State
+	} else {
+		logger.LogTraceId = newTraceId()
+	}
+
+	return logger
+}
+
+// TraceContext returns the context to pass on to requests sent by this logger's operation: The span of this logger
+// becomes the parent of the receiver's span.
+func (l *Logger) TraceContext() TraceContext {
+	return TraceContext{
+		TraceId:  l.LogTraceId,
+		ParentId: l.LogSpanId,
+		Flags:    l.TraceFlags,
+		State:    l.TraceState,
+	}
+}
+
+// InjectTraceContext sets the traceparent and tracestate headers of the outgoing request according to this logger.
+// When the logger has no span, a random span ID is used as parent ID. Nothing is set for the trace ID 0, which is
+// invalid in the W3C trace context.
+func (l *Logger) InjectTraceContext(r *http.Request) {
+	context := l.TraceContext()
+	if context.TraceId.IsZero() {
+		return
+	}
+	if context.ParentId == 0 {
+		context.ParentId = NewSpanId()
+	}
+
+	if r.Header == nil {
+		r.Header = http.Header{}
+	}
+	r.Header.Set(TraceparentHeader, context.Traceparent())
+	if len(context.State) > 0 {
+		r.Header.Set(TracestateHeader, context.State.String())
+	} else {
+		r.Header.Del(TracestateHeader)
+	}
+}
+
+// InjectTraceContext sets the trace context headers of the request according to the DefaultLogger, see
+// Logger.InjectTraceContext for details.
+func InjectTraceContext(r *http.Request) {
+	DefaultLogger.InjectTraceContext(r)
+}
@@ -0,0 +1,97 @@
+package sigolo
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	context, err := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertTrue(t, context.TraceId.String() == "4bf92f3577b34da6a3ce929d0e0e4736")
+	assertTrue(t, context.ParentId == 0x00f067aa0ba902b7)
+	assertTrue(t, context.Flags == TRACE_FLAG_SAMPLED)
+	assertTrue(t, context.Traceparent() == "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	_, err = ParseTraceparent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-future")
+	assertTrue(t, err == nil)
+
+	for _, invalid := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-future",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00_4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	} {
+		_, err = ParseTraceparent(invalid)
+		if err == nil {
+			t.Errorf("Expected %q to be invalid", invalid)
+		}
+	}
+}
+
+func TestParseTraceState(t *testing.T) {
+	state, err := ParseTraceState("rojo=00f067aa0ba902b7, ,tenant@congo=t61rcWkgMzE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertTrue(t, state.Get("tenant@congo") == "t61rcWkgMzE")
+	assertTrue(t, state.String() == "rojo=00f067aa0ba902b7,tenant@congo=t61rcWkgMzE")
+
+	for _, invalid := range []string{"Rojo=1", "rojo", "rojo=a,rojo=b", "rojo=a=b", "rojo=caf\u00e9"} {
+		_, err = ParseTraceState(invalid)
+		if err == nil {
+			t.Errorf("Expected %q to be invalid", invalid)
+		}
+	}
+}
+
+func TestLoggerFromRequestAndInjection(t *testing.T) {
+	incoming, _ := http.NewRequest(http.MethodGet, "http://localhost/", nil)
+	incoming.Header.Set(TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	incoming.Header.Add(TracestateHeader, "rojo=00f067aa0ba902b7")
+	incoming.Header.Add(TracestateHeader, "congo=t61rcWkgMzE")
+
+	nextTraceId := GetCurrentNextTraceId()
+	logger := NewLoggerFromRequest(incoming)
+	assertTrue(t, GetCurrentNextTraceId() == nextTraceId)
+	assertTrue(t, logger.LogTraceId.String() == "4bf92f3577b34da6a3ce929d0e0e4736")
+	assertTrue(t, logger.LogParentSpanId == 0x00f067aa0ba902b7)
+	assertTrue(t, logger.LogSpanId != 0 && logger.LogSpanId != logger.LogParentSpanId)
+
+	outgoing, _ := http.NewRequest(http.MethodGet, "http://localhost/", nil)
+	logger.InjectTraceContext(outgoing)
+	assertTrue(t, outgoing.Header.Get(TraceparentHeader) == "00-4bf92f3577b34da6a3ce929d0e0e4736-"+logger.LogSpanId.String()+"-01")
+	assertTrue(t, outgoing.Header.Get(TracestateHeader) == "rojo=00f067aa0ba902b7,congo=t61rcWkgMzE")
+}
+
+func TestLoggerFromRequestWithoutTraceparent(t *testing.T) {
+	incoming, _ := http.NewRequest(http.MethodGet, "http://localhost/", nil)
+	incoming.Header.Set(TraceparentHeader, "invalid")
+
+	logger := NewLoggerFromRequest(incoming)
+	assertTrue(t, logger.LogTraceId.Format == TRACE_ID_SEQUENTIAL)
+	assertTrue(t, logger.LogParentSpanId == 0)
+	assertTrue(t, logger.LogSpanId != 0)
+
+	// Sequential trace IDs are padded to 32 digits.
+	outgoing, _ := http.NewRequest(http.MethodGet, "http://localhost/", nil)
+	logger.LogTraceId = SequentialTraceId(0x2a)
+	logger.InjectTraceContext(outgoing)
+	assertTrue(t, strings.HasPrefix(outgoing.Header.Get(TraceparentHeader), "00-0000000000000000000000000000002a-"))
+}
+
+func TestSpanIdIsRendered(t *testing.T) {
+	logger, buffer := newBufferLogger(LOG_INFO)
+	logger.LogTraceId = SequentialTraceId(0x2a)
+	logger.LogSpanId = 0x00f067aa0ba902b7
+
+	logger.Info("foo")
+
+	assertTrue(t, strings.HasSuffix(buffer.String(), "| #2a/00f067aa0ba902b7 | foo\n"))
+}
@@ -0,0 +1,246 @@
+package sigolo
+
+import (
+	"errors"
+	"math/bits"
+	"math/rand/v2"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// TraceIdFormat determines how a trace ID is rendered.
+type TraceIdFormat int
+
+const (
+	// TRACE_ID_SEQUENTIAL renders the lower 64 bits as hex number without leading zeros, e.g. "2a".
+	TRACE_ID_SEQUENTIAL TraceIdFormat = iota
+	// TRACE_ID_HEX64 renders the lower 64 bits as 16 hex digits, e.g. "00f067aa0ba902b7".
+	TRACE_ID_HEX64
+	// TRACE_ID_HEX128 renders all 128 bits as 32 hex digits like W3C trace IDs, e.g. "4bf92f3577b34da6a3ce929d0e0e4736".
+	TRACE_ID_HEX128
+	// TRACE_ID_ULID renders all 128 bits as 26 characters of Crockford's base32, e.g. "01ARZ3NDEKTSV4RRFFQ69G5FAV".
+	TRACE_ID_ULID
+)
+
+// TraceId identifies all entries belonging together, e.g. the entries of one request. It holds up to 128 bits and
+// knows how it's rendered. The zero value is the sequential ID 0.
+type TraceId struct {
+	High   uint64
+	Low    uint64
+	Format TraceIdFormat
+}
+
+// SequentialTraceId returns the trace ID with the given number, like the ones of the SequentialTraceIdGenerator.
+func SequentialTraceId(number uint64) TraceId {
+	return TraceId{Low: number}
+}
+
+const crockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// Append appends the rendered trace ID to the buffer.
+func (t TraceId) Append(buffer []byte) []byte {
+	switch t.Format {
+	case TRACE_ID_HEX64:
+		return appendHex(buffer, t.Low)
+	case TRACE_ID_HEX128:
+		return appendHex(appendHex(buffer, t.High), t.Low)
+	case TRACE_ID_ULID:
+		// 26 characters of 5 bits each are 130 bits, so the first character holds only the top 3 bits.
+		for i := 25; i >= 0; i-- {
+			shift := uint(i * 5)
+			var value uint64
+			if shift >= 64 {
+				value = t.High >> (shift - 64)
+			} else {
+				value = t.Low>>shift | t.High<<(64-shift)
+			}
+			buffer = append(buffer, crockfordAlphabet[value&0x1f])
+		}
+		return buffer
+	default:
+		return strconv.AppendUint(buffer, t.Low, 16)
+	}
+}
+
+func appendHex(buffer []byte, value uint64) []byte {
+	const digits = "0123456789abcdef"
+	for shift := 60; shift >= 0; shift -= 4 {
+		buffer = append(buffer, digits[(value>>uint(shift))&0xf])
+	}
+	return buffer
+}
+
+func (t TraceId) String() string {
+	var buffer [32]byte
+	return string(t.Append(buffer[:0]))
+}
+
+// MarshalText renders the trace ID like String does.
+func (t TraceId) MarshalText() ([]byte, error) {
+	return t.Append(nil), nil
+}
+
+// UnmarshalText parses the trace ID, see ParseTraceId.
+func (t *TraceId) UnmarshalText(text []byte) error {
+	traceId, err := ParseTraceId(string(text))
+	if err != nil {
+		return err
+	}
+	*t = traceId
+	return nil
+}
+
+// IsZero returns true when all bits of the trace ID are 0.
+func (t TraceId) IsZero() bool {
+	return t.High == 0 && t.Low == 0
+}
+
+// ParseTraceId parses a rendered trace ID. The format is determined by the length: 32 characters are TRACE_ID_HEX128,
+// 26 characters are TRACE_ID_ULID, 16 characters are TRACE_ID_HEX64 and shorter ones are TRACE_ID_SEQUENTIAL.
+func ParseTraceId(text string) (TraceId, error) {
+	switch {
+	case len(text) == 32:
+		high, err := strconv.ParseUint(text[:16], 16, 64)
+		if err != nil {
+			return TraceId{}, err
+		}
+		low, err := strconv.ParseUint(text[16:], 16, 64)
+		if err != nil {
+			return TraceId{}, err
+		}
+		return TraceId{High: high, Low: low, Format: TRACE_ID_HEX128}, nil
+	case len(text) == 26:
+		return parseUlid(text)
+	case len(text) == 16:
+		low, err := strconv.ParseUint(text, 16, 64)
+		return TraceId{Low: low, Format: TRACE_ID_HEX64}, err
+	case len(text) > 0 && len(text) < 16:
+		low, err := strconv.ParseUint(text, 16, 64)
+		return TraceId{Low: low}, err
+	}
+	return TraceId{}, errors.New("invalid trace ID length " + strconv.Itoa(len(text)))
+}
+
+func parseUlid(text string) (TraceId, error) {
+	traceId := TraceId{Format: TRACE_ID_ULID}
+	for i := 0; i < len(text); i++ {
+		value := crockfordValue(text[i])
+		if value < 0 || (i == 0 && value > 7) {
+			return TraceId{}, errors.New("invalid ULID character " + strconv.QuoteRune(rune(text[i])))
+		}
+		traceId.High = traceId.High<<5 | traceId.Low>>59
+		traceId.Low = traceId.Low<<5 | uint64(value)
+	}
+	return traceId, nil
+}
+
+func crockfordValue(c byte) int {
+	if c >= 'a' && c <= 'z' {
+		c -= 'a' - 'A'
+	}
+	for i := 0; i < len(crockfordAlphabet); i++ {
+		if crockfordAlphabet[i] == c {
+			return i
+		}
+	}
+	return -1
+}
+
+// TraceIdGenerator creates trace IDs for new loggers and the entries of the package level functions. Implementations
+// must be safe for concurrent use.
+type TraceIdGenerator interface {
+	NextTraceId() TraceId
+}
+
+// SequentialTraceIdGenerator counts upwards. This is the default generator and the IDs are only unique within one
+// process.
+type SequentialTraceIdGenerator struct {
+	next atomic.Uint64
+}
+
+// NewSequentialTraceIdGenerator creates a generator starting with the given number.
+func NewSequentialTraceIdGenerator(start uint64) *SequentialTraceIdGenerator {
+	generator := &SequentialTraceIdGenerator{}
+	generator.next.Store(start)
+	return generator
+}
+
+func (g *SequentialTraceIdGenerator) NextTraceId() TraceId {
+	return SequentialTraceId(g.next.Add(1) - 1)
+}
+
+type randomTraceIdGenerator struct {
+	format TraceIdFormat
+}
+
+// NewRandom64TraceIdGenerator creates a generator of random 64 bit IDs rendered as TRACE_ID_HEX64.
+func NewRandom64TraceIdGenerator() TraceIdGenerator {
+	return randomTraceIdGenerator{format: TRACE_ID_HEX64}
+}
+
+// NewRandom128TraceIdGenerator creates a generator of random 128 bit IDs rendered as TRACE_ID_HEX128. The IDs are
+// never all zero and therefore valid trace-ids of the W3C trace context.
+func NewRandom128TraceIdGenerator() TraceIdGenerator {
+	return randomTraceIdGenerator{format: TRACE_ID_HEX128}
+}
+
+// NewW3CTraceIdGenerator is equal to NewRandom128TraceIdGenerator.
+func NewW3CTraceIdGenerator() TraceIdGenerator {
+	return NewRandom128TraceIdGenerator()
+}
+
+func (g randomTraceIdGenerator) NextTraceId() TraceId {
+	for {
+		traceId := TraceId{Low: rand.Uint64(), Format: g.format}
+		if g.format == TRACE_ID_HEX128 {
+			traceId.High = rand.Uint64()
+		}
+		// An ID of all zeros is invalid in the W3C trace context.
+		if !traceId.IsZero() {
+			return traceId
+		}
+	}
+}
+
+// UlidTraceIdGenerator creates ULIDs, which consist of a 48 bit timestamp in milliseconds and 80 random bits. IDs
+// created within the same millisecond are increasing.
+type UlidTraceIdGenerator struct {
+	// Clock provides the timestamps. When nil, time.Now() is used.
+	Clock Clock
+
+	mutex sync.Mutex
+	last  TraceId
+}
+
+// NewUlidTraceIdGenerator creates a generator of ULIDs.
+func NewUlidTraceIdGenerator() *UlidTraceIdGenerator {
+	return &UlidTraceIdGenerator{}
+}
+
+func (g *UlidTraceIdGenerator) NextTraceId() TraceId {
+	now := time.Now()
+	if g.Clock != nil {
+		now = g.Clock.Now()
+	}
+	timestamp := uint64(now.UnixMilli()) & (1<<48 - 1)
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if g.last.High>>16 == timestamp {
+		// Increment the random part of the previous ID to keep the order within the same millisecond.
+		low, carry := bits.Add64(g.last.Low, 1, 0)
+		g.last.Low = low
+		g.last.High += carry
+		return g.last
+	}
+
+	g.last = TraceId{
+		High:   timestamp<<16 | rand.Uint64()&0xffff,
+		Low:    rand.Uint64(),
+		Format: TRACE_ID_ULID,
+	}
+	return g.last
+}
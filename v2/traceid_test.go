@@ -0,0 +1,106 @@
+package sigolo
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTraceIdRendering(t *testing.T) {
+	traceId := TraceId{High: 0x4bf92f3577b34da6, Low: 0xa3ce929d0e0e4736}
+
+	assertTrue(t, SequentialTraceId(0x2a).String() == "2a")
+	assertTrue(t, TraceId{Low: 0x2a, Format: TRACE_ID_HEX64}.String() == "000000000000002a")
+	traceId.Format = TRACE_ID_HEX128
+	assertTrue(t, traceId.String() == "4bf92f3577b34da6a3ce929d0e0e4736")
+	assertTrue(t, TraceId{High: 1469918176385 << 16, Format: TRACE_ID_ULID}.String() == "01ARYZ6S410000000000000000")
+}
+
+func TestParseTraceId(t *testing.T) {
+	for _, traceId := range []TraceId{
+		SequentialTraceId(0x2a),
+		{Low: 0xa3ce929d0e0e4736, Format: TRACE_ID_HEX64},
+		{High: 0x4bf92f3577b34da6, Low: 0xa3ce929d0e0e4736, Format: TRACE_ID_HEX128},
+		{High: 0x4bf92f3577b34da6, Low: 0xa3ce929d0e0e4736, Format: TRACE_ID_ULID},
+	} {
+		parsed, err := ParseTraceId(traceId.String())
+		if err != nil || parsed != traceId {
+			t.Errorf("Expected %#v but got %#v (%v)", traceId, parsed, err)
+		}
+	}
+
+	_, err := ParseTraceId("")
+	assertTrue(t, err != nil)
+	_, err = ParseTraceId("8ZZZZZZZZZZZZZZZZZZZZZZZZZ")
+	assertTrue(t, err != nil)
+}
+
+func TestTraceIdGenerators(t *testing.T) {
+	sequential := NewSequentialTraceIdGenerator(5)
+	assertTrue(t, sequential.NextTraceId() == SequentialTraceId(5))
+	assertTrue(t, sequential.NextTraceId() == SequentialTraceId(6))
+
+	random := NewRandom64TraceIdGenerator().NextTraceId()
+	assertTrue(t, len(random.String()) == 16 && random.High == 0)
+	w3c := NewW3CTraceIdGenerator().NextTraceId()
+	assertTrue(t, len(w3c.String()) == 32 && !w3c.IsZero())
+
+	ulids := NewUlidTraceIdGenerator()
+	ulids.Clock = NewFakeClock(time.UnixMilli(1469918176385))
+	first := ulids.NextTraceId().String()
+	second := ulids.NextTraceId().String()
+	assertTrue(t, len(first) == 26 && strings.HasPrefix(first, "01ARYZ6S41"))
+	assertTrue(t, second > first)
+}
+
+func TestDefaultTraceIdGenerator(t *testing.T) {
+	SetDefaultTraceIdGenerator(NewW3CTraceIdGenerator())
+	defer SetDefaultTraceIdGenerator(NewSequentialTraceIdGenerator(0))
+
+	first := DefaultLogger.LogTraceId
+	Info("foo")
+	second := DefaultLogger.LogTraceId
+
+	assertTrue(t, len(first.String()) == 32 && len(second.String()) == 32)
+	assertTrue(t, first != second)
+	assertTrue(t, NewLogger().LogTraceId.Format == TRACE_ID_HEX128)
+}
+
+func TestNewTraceIdIsSafeForConcurrentUse(t *testing.T) {
+	ids := make(chan TraceId, 400)
+	wait := sync.WaitGroup{}
+	for i := 0; i < 4; i++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			for j := 0; j < 100; j++ {
+				ids <- NewLogger().LogTraceId
+			}
+		}()
+	}
+	wait.Wait()
+	close(ids)
+
+	seen := map[TraceId]bool{}
+	for id := range ids {
+		assertFalse(t, seen[id])
+		seen[id] = true
+	}
+}
+
+func TestLogDefaultRendersTraceId(t *testing.T) {
+	buffer := &strings.Builder{}
+	traceId := TraceId{High: 0x4bf92f3577b34da6, Low: 0xa3ce929d0e0e4736, Format: TRACE_ID_HEX128}
+
+	LogDefault(buffer, FormattedEntry{
+		Time:        "time",
+		Level:       LOG_INFO,
+		LevelString: "[INFO] ",
+		Caller:      "foo.go:1",
+		TraceId:     traceId,
+		Message:     "foo",
+	})
+
+	assertTrue(t, strings.HasSuffix(buffer.String(), "| #4bf92f3577b34da6a3ce929d0e0e4736 | foo\n"))
+}
@@ -0,0 +1,159 @@
+package sigolo
+
+import (
+	"bytes"
+	"log"
+	"sync"
+	"unicode/utf8"
+)
+
+// LevelWriter is an io.Writer turning everything written to it into log entries of one level. The written data is split
+// into lines and each line becomes its own entry. Incomplete lines are kept until the rest of the line arrives or Flush
+// is called.
+type LevelWriter struct {
+	// Prefix is put in front of every logged line.
+	Prefix string
+	// MaxLineLength is the maximum number of bytes logged per entry. Longer lines are split into several entries. A value
+	// of 0 disables the limit.
+	MaxLineLength int
+
+	logger *Logger
+	level  Level
+	buffer []byte
+	mutex  sync.Mutex
+
+	// callerDetails determines the caller shown for each line. It gets the number of frames between itself and the
+	// function that called Write.
+	callerDetails func(framesBackward int, format CallerFormat) string
+}
+
+// NewLevelWriter creates a writer logging each line with the given level using the given logger. When the logger is
+// nil, the DefaultLogger is used and, like all the package level functions do, each line gets its own trace ID.
+func NewLevelWriter(logger *Logger, level Level) *LevelWriter {
+	return &LevelWriter{
+		logger:        logger,
+		level:         level,
+		callerDetails: getCallerDetails,
+	}
+}
+
+// Writer returns an io.Writer logging each line written to it with the given level.
+func (l *Logger) Writer(level Level) *LevelWriter {
+	return NewLevelWriter(l, level)
+}
+
+// Write logs every complete line within p. The caller of Write is used as caller information of the entries.
+func (w *LevelWriter) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.buffer = append(w.buffer, p...)
+
+	lineEnd := bytes.IndexByte(w.buffer, '\n')
+	if lineEnd == -1 && !w.exceedsMaxLineLength(w.buffer) {
+		return len(p), nil
+	}
+
+	// Frames: callerDetails, Write, the function calling Write
+	caller := w.callerDetails(2, w.callerFormat())
+
+	lineStart := 0
+	for lineEnd != -1 {
+		line := bytes.TrimSuffix(w.buffer[lineStart:lineStart+lineEnd], []byte{'\r'})
+		for w.exceedsMaxLineLength(line) {
+			line = line[w.logChunk(caller, line):]
+		}
+		w.logLine(caller, line)
+		lineStart += lineEnd + 1
+		lineEnd = bytes.IndexByte(w.buffer[lineStart:], '\n')
+	}
+
+	for w.exceedsMaxLineLength(w.buffer[lineStart:]) {
+		lineStart += w.logChunk(caller, w.buffer[lineStart:])
+	}
+
+	// Move the remaining incomplete line to the front so that the buffer doesn't grow forever.
+	w.buffer = append(w.buffer[:0], w.buffer[lineStart:]...)
+
+	return len(p), nil
+}
+
+// logChunk logs the first MaxLineLength bytes of the line, or less to not split a multi-byte character, and returns the
+// number of logged bytes.
+func (w *LevelWriter) logChunk(caller string, line []byte) int {
+	chunkEnd := w.MaxLineLength
+	for chunkEnd > 1 && !utf8.RuneStart(line[chunkEnd]) {
+		chunkEnd--
+	}
+	w.logLine(caller, line[:chunkEnd])
+	return chunkEnd
+}
+
+// Flush logs the currently buffered incomplete line, if there is one.
+func (w *LevelWriter) Flush() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if len(w.buffer) == 0 {
+		return
+	}
+
+	w.logLine(w.callerDetails(2, w.callerFormat()), w.buffer)
+	w.buffer = w.buffer[:0]
+}
+
+func (w *LevelWriter) callerFormat() CallerFormat {
+	if w.logger == nil {
+		return DefaultLogger.CallerFormat
+	}
+	return w.logger.CallerFormat
+}
+
+func (w *LevelWriter) exceedsMaxLineLength(line []byte) bool {
+	return w.MaxLineLength > 0 && len(line) > w.MaxLineLength
+}
+
+func (w *LevelWriter) logLine(caller string, line []byte) {
+	line = bytes.TrimSuffix(line, []byte{'\r'})
+	message := w.Prefix + string(line)
+
+	if w.logger == nil {
+		if !DefaultLogger.shouldLogSampled(w.level) {
+			increaseTraceId()
+			return
+		}
+		DefaultLogger.logCaller(w.level, caller, DefaultLogger.LogTraceId, message)
+		increaseTraceId()
+		return
+	}
+
+	if !w.logger.shouldLogSampled(w.level) {
+		return
+	}
+	w.logger.logCaller(w.level, caller, w.logger.LogTraceId, message)
+}
+
+// RedirectStdLog sets the output of the standard "log" package to the DefaultLogger using the INFO level. The flags and
+// prefix of the standard logger are removed as sigolo already adds time and caller information.
+func RedirectStdLog() {
+	redirectStdLog(NewLevelWriter(nil, LOG_INFO))
+}
+
+// RedirectStdLog sets the output of the standard "log" package to this logger using the given level. The flags and
+// prefix of the standard logger are removed as sigolo already adds time and caller information.
+func (l *Logger) RedirectStdLog(level Level) {
+	redirectStdLog(l.Writer(level))
+}
+
+func redirectStdLog(writer *LevelWriter) {
+	writer.callerDetails = getStdLogCallerDetails
+	log.SetFlags(0)
+	log.SetPrefix("")
+	log.SetOutput(writer)
+}
+
+// getStdLogCallerDetails skips all frames of the standard "log" package and returns the caller details of the first
+// frame outside of it, which is the function that called e.g. log.Printf.
+func getStdLogCallerDetails(framesBackward int, format CallerFormat) string {
+	return getCallerDetailsSkipping(framesBackward+1, format, "log.")
+}
@@ -0,0 +1,102 @@
+package sigolo
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+func newBufferLogger(level Level) (*Logger, *bytes.Buffer) {
+	buffer := &bytes.Buffer{}
+	logger := NewLoggerl(level)
+	for l := range logger.LevelOutputs {
+		logger.LevelOutputs[l] = buffer
+	}
+	return logger, buffer
+}
+
+func TestLevelWriter(t *testing.T) {
+	logger, buffer := newBufferLogger(LOG_INFO)
+	writer := logger.Writer(LOG_WARN)
+
+	writer.Write([]byte("first\nsecond\r\nthi"))
+	writer.Write([]byte("rd"))
+
+	lines := strings.Split(strings.TrimSuffix(buffer.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines but got %d: %q", len(lines), buffer.String())
+	}
+	assertTrue(t, strings.HasSuffix(lines[0], "| first"))
+	assertTrue(t, strings.HasSuffix(lines[1], "| second"))
+	assertTrue(t, strings.Contains(lines[0], "[WARN]"))
+	assertTrue(t, strings.Contains(lines[0], "writer_test.go"))
+
+	writer.Flush()
+
+	assertTrue(t, strings.HasSuffix(buffer.String(), "| third\n"))
+}
+
+func TestLevelWriterRespectsLevel(t *testing.T) {
+	logger, buffer := newBufferLogger(LOG_INFO)
+	writer := logger.Writer(LOG_DEBUG)
+
+	writer.Write([]byte("not visible\n"))
+
+	assertTrue(t, buffer.Len() == 0)
+}
+
+func TestRedirectStdLog(t *testing.T) {
+	SetDefaultLogLevel(LOG_PLAIN)
+	buffer := &bytes.Buffer{}
+	levelOutputs[LOG_INFO] = buffer
+	defer func() {
+		levelOutputs[LOG_INFO] = os.Stdout
+		log.SetOutput(os.Stderr)
+		log.SetFlags(log.LstdFlags)
+	}()
+
+	RedirectStdLog()
+	log.Printf("foo %d", 123)
+
+	output := buffer.String()
+	assertTrue(t, strings.HasSuffix(output, "| foo 123\n"))
+	assertTrue(t, strings.Contains(output, "[INFO]"))
+	assertTrue(t, strings.Contains(output, "writer_test.go"))
+}
+
+func TestLevelWriterSplitsLongLines(t *testing.T) {
+	logger, buffer := newBufferLogger(LOG_INFO)
+	writer := logger.Writer(LOG_INFO)
+	writer.MaxLineLength = 4
+
+	writer.Write([]byte("abcdefghij"))
+
+	lines := strings.Split(strings.TrimSuffix(buffer.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines but got %d: %q", len(lines), buffer.String())
+	}
+	assertTrue(t, strings.HasSuffix(lines[0], "| abcd"))
+	assertTrue(t, strings.HasSuffix(lines[1], "| efgh"))
+
+	writer.Flush()
+	assertTrue(t, strings.HasSuffix(buffer.String(), "| ij\n"))
+}
+
+func TestLevelWriterSplitsLongCompleteLines(t *testing.T) {
+	logger, buffer := newBufferLogger(LOG_INFO)
+	writer := logger.Writer(LOG_INFO)
+	writer.MaxLineLength = 4
+
+	writer.Write([]byte("abcdefghij\r\nkl\n"))
+
+	lines := strings.Split(strings.TrimSuffix(buffer.String(), "\n"), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("Expected 4 lines but got %d: %q", len(lines), buffer.String())
+	}
+	assertTrue(t, strings.HasSuffix(lines[0], "| abcd"))
+	assertTrue(t, strings.HasSuffix(lines[1], "| efgh"))
+	assertTrue(t, strings.HasSuffix(lines[2], "| ij"))
+	assertTrue(t, strings.HasSuffix(lines[3], "| kl"))
+}